@@ -0,0 +1,87 @@
+package regtest
+
+import (
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+const (
+	nActivatorTests = 100
+	activatorScale  = 3
+)
+
+// Activator is an activation function of a neural-net style regressor
+type Activator interface {
+	Activate(x float64) float64
+	Deriv(x float64) float64
+}
+
+// CombinedActivator is an Activator that can compute the activation and its
+// derivative in a single call
+type CombinedActivator interface {
+	Activator
+	ActivateDeriv(x float64) (a, d float64)
+}
+
+// VecActivator is an Activator with vectorized forms that store the elementwise
+// activation (or derivative) of x in dst
+type VecActivator interface {
+	Activator
+	ActivateVec(dst, x []float64)
+	DerivVec(dst, x []float64)
+}
+
+// TestActivator tests that Deriv matches finite difference of Activate at random
+// points, with the step and tolerance set by the FDStep and FDTol options, reporting
+// the error at a sweep of step sizes on failure. If the activator is also a
+// CombinedActivator, it checks that ActivateDeriv agrees with separate calls, and if
+// it is a VecActivator, that the vectorized forms agree with elementwise application.
+func TestActivator(t *testing.T, a Activator, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	x := make([]float64, nActivatorTests)
+	act := make([]float64, nActivatorTests)
+	deriv := make([]float64, nActivatorTests)
+	activate := scalar(func(x []float64) float64 { return a.Activate(x[0]) })
+	for i := range x {
+		x[i] = activatorScale * rnd.NormFloat64()
+		act[i] = a.Activate(x[i])
+		deriv[i] = a.Deriv(x[i])
+
+		// A scalar function can't return the wrong size
+		at := x[i : i+1]
+		fd, _ := fdJacobianSteps(activate, at, 1, fdSteps(at, s.fdStep, false))
+		if !floats.EqualWithinAbsOrRel(fd.At(0, 0), deriv[i], s.fdTol, s.fdTol) {
+			t.Errorf("%v: deriv doesn't match at %v: Finite Difference: %v, Analytic: %v%v", name, x[i], fd.At(0, 0), deriv[i], gradientSweep(activate, at, false, deriv[i:i+1]))
+			return
+		}
+	}
+
+	if c, ok := a.(CombinedActivator); ok {
+		for i := range x {
+			ac, d := c.ActivateDeriv(x[i])
+			if !s.tol.equal(ac, act[i]) || !s.tol.equal(d, deriv[i]) {
+				t.Errorf("%v: ActivateDeriv at %v doesn't match separate calls. Combined: (%v, %v), separate: (%v, %v)", name, x[i], ac, d, act[i], deriv[i])
+				return
+			}
+		}
+	}
+
+	if v, ok := a.(VecActivator); ok {
+		xCpy := make([]float64, len(x))
+		copy(xCpy, x)
+		dst := make([]float64, len(x))
+		v.ActivateVec(dst, x)
+		if d := s.tol.diff(act, dst); d != "" {
+			t.Errorf("%v: ActivateVec doesn't match elementwise Activate: %v", name, d)
+		}
+		v.DerivVec(dst, x)
+		if d := s.tol.diff(deriv, dst); d != "" {
+			t.Errorf("%v: DerivVec doesn't match elementwise Deriv: %v", name, d)
+		}
+		if !floats.Equal(x, xCpy) {
+			t.Errorf("%v: input modified by vectorized activation", name)
+		}
+	}
+}
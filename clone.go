@@ -0,0 +1,94 @@
+package regtest
+
+import (
+	"testing"
+)
+
+const nProbes = 10
+
+// ParameterPredictor is a model whose predictions depend on its parameters
+type ParameterPredictor interface {
+	Predictor
+	ParameterGetterSetter
+}
+
+// Cloner is a model that can make a deep copy of itself. The value returned by
+// Clone must implement ParameterPredictor. It is typed as interface{} so that
+// models can return their concrete type without importing this package.
+type Cloner interface {
+	ParameterPredictor
+	Clone() interface{}
+}
+
+// TestClone tests that Clone makes a deep copy. The clone must start with the same
+// parameters and predictions as the original, and afterwards setting the parameters
+// of either one must not change the parameters or predictions of the other. The
+// parameters of the original are restored before returning.
+func TestClone(t *testing.T, c Cloner, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	probes := randomProbes(nProbes, c.InputDim(), rnd)
+	origParams := c.Parameters(nil)
+	defer c.SetParameters(origParams)
+	origPred, err := predictAll(c, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with original: %v", name, err)
+		return
+	}
+
+	clone, ok := c.Clone().(ParameterPredictor)
+	if !ok {
+		t.Errorf("%v: Clone did not return a ParameterPredictor", name)
+		return
+	}
+	cloneParams := clone.Parameters(nil)
+	if d := s.tol.diff(origParams, cloneParams); d != "" {
+		t.Errorf("%v: Clone has different parameters than the original: %v", name, d)
+		return
+	}
+	clonePred, err := predictAll(clone, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with clone: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(origPred, clonePred); d != "" {
+		t.Errorf("%v: Clone has different predictions than the original: %v", name, d)
+		return
+	}
+
+	if c.NumParameters() == 0 {
+		return
+	}
+
+	clone.SetParameters(randomParameters(clone, rnd))
+	if d := s.tol.diff(origParams, c.Parameters(nil)); d != "" {
+		t.Errorf("%v: Setting the parameters of the clone changed the parameters of the original: %v", name, d)
+	}
+	pred, err := predictAll(c, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with original: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(origPred, pred); d != "" {
+		t.Errorf("%v: Setting the parameters of the clone changed the predictions of the original: %v", name, d)
+	}
+
+	cloneParams = clone.Parameters(nil)
+	clonePred, err = predictAll(clone, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with clone: %v", name, err)
+		return
+	}
+	c.SetParameters(randomParameters(c, rnd))
+	if d := s.tol.diff(cloneParams, clone.Parameters(nil)); d != "" {
+		t.Errorf("%v: Setting the parameters of the original changed the parameters of the clone: %v", name, d)
+	}
+	pred, err = predictAll(clone, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with clone: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(clonePred, pred); d != "" {
+		t.Errorf("%v: Setting the parameters of the original changed the predictions of the clone: %v", name, d)
+	}
+}
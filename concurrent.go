@@ -0,0 +1,135 @@
+package regtest
+
+import (
+	"sync"
+	"testing"
+)
+
+const (
+	nGoroutines       = 8
+	nConcurrentCycles = 200
+	nWrittenVectors   = 4
+)
+
+// TestParametersConcurrent hammers Parameters and SetParameters from multiple goroutines,
+// for models that document thread-safety; it is intended to be run under -race. Writers
+// set parameters from a fixed set of random vectors, and every snapshot returned by
+// Parameters must equal one of those vectors (or the initial parameters) to within the
+// round trip tolerance, never a torn mix. The initial parameters are restored before returning.
+func TestParametersConcurrent(t *testing.T, p ParameterGetterSetter, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if p.NumParameters() == 0 {
+		return
+	}
+	orig := p.Parameters(nil)
+	defer p.SetParameters(orig)
+	valid := [][]float64{orig}
+	for i := 0; i < nWrittenVectors; i++ {
+		valid = append(valid, randomParameters(p, rnd))
+	}
+
+	var mu sync.Mutex
+	var torn []float64
+	wg := &sync.WaitGroup{}
+	wg.Add(2 * nGoroutines)
+	for g := 0; g < nGoroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < nConcurrentCycles; i++ {
+				p.SetParameters(valid[1+(g+i)%nWrittenVectors])
+			}
+		}(g)
+		go func() {
+			defer wg.Done()
+			dst := make([]float64, p.NumParameters())
+			for i := 0; i < nConcurrentCycles; i++ {
+				p.Parameters(dst)
+				if !isOneOf(dst, valid, s.roundTrip) {
+					mu.Lock()
+					if torn == nil {
+						torn = make([]float64, len(dst))
+						copy(torn, dst)
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if torn != nil {
+		t.Errorf("%v: concurrent Parameters returned a torn snapshot %v", name, torn)
+	}
+}
+
+// isOneOf returns whether x matches one of the candidates to within tol
+func isOneOf(x []float64, candidates [][]float64, tol Tolerance) bool {
+	for _, c := range candidates {
+		if tol.equalSlices(x, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestPredictConcurrent tests models that allow Predict to run concurrently with
+// SetParameters. While many goroutines call Predict on random inputs, the calling
+// goroutine repeatedly sets the parameters to one of a fixed set of random vectors and
+// checks that Parameters then returns exactly that vector, never a mix of old and new
+// values. It is intended to be run under -race. The initial parameters are restored
+// before returning.
+func TestPredictConcurrent(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if p.NumParameters() == 0 {
+		return
+	}
+	orig := p.Parameters(nil)
+	defer p.SetParameters(orig)
+	written := make([][]float64, nWrittenVectors)
+	for i := range written {
+		written[i] = randomParameters(p, rnd)
+	}
+	probes := randomProbes(nProbes, p.InputDim(), rnd)
+
+	done := make(chan struct{})
+	errs := make(chan error, nGoroutines)
+	wg := &sync.WaitGroup{}
+	wg.Add(nGoroutines)
+	for g := 0; g < nGoroutines; g++ {
+		go func() {
+			defer wg.Done()
+			output := make([]float64, p.OutputDim())
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := p.Predict(probes[i%len(probes)], output); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	snapshot := make([]float64, p.NumParameters())
+	for i := 0; i < nConcurrentCycles; i++ {
+		want := written[i%nWrittenVectors]
+		p.SetParameters(want)
+		p.Parameters(snapshot)
+		if d := s.roundTrip.diff(want, snapshot); d != "" {
+			t.Errorf("%v: Parameters during concurrent Predict don't match the parameters set: %v", name, d)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("%v: Error predicting concurrently: %v", name, err)
+		break
+	}
+}
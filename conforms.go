@@ -0,0 +1,108 @@
+package regtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// Conforms detects, by type assertion, every interface in this package that model
+// implements and runs each applicable suite as a subtest, so that adding a method to a
+// model automatically gains coverage. Only suites that need nothing beyond the model
+// are run; suites that need training data, a fresh model or a declared contract (e.g.
+// TestTrain, TestSerializeParameters, TestParameterBounds) must still be called
+// directly. TestParametersStress is skipped for Bounded models, whose bounds clamp or
+// reject its extreme values, and TestParametersNoAlloc is run only with the AllocChecks
+// option. The parameters of the model are restored after each suite. The options are
+// passed to the suites that accept them.
+func Conforms(t *testing.T, model interface{}, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	// Pass the seed on so that the one logged here reproduces every suite
+	opts = append(opts[:len(opts):len(opts)], Seed(s.seed))
+	name := fmt.Sprintf("%T", model)
+	ran := false
+	run := func(suite string, f func(t *testing.T)) {
+		ran = true
+		t.Run(suite, func(t *testing.T) {
+			if p, ok := model.(ParameterGetterSetter); ok {
+				orig := p.Parameters(nil)
+				defer p.SetParameters(orig)
+			}
+			f(t)
+		})
+	}
+
+	if p, ok := model.(ParameterGetterSetter); ok {
+		run("GetAndSetParameters", func(t *testing.T) { TestGetAndSetParameters(t, p, name, opts...) })
+		if _, bounded := model.(Bounded); !bounded {
+			run("ParametersStress", func(t *testing.T) { TestParametersStress(t, p, name, opts...) })
+		}
+		if s.allocChecks {
+			run("ParametersNoAlloc", func(t *testing.T) { TestParametersNoAlloc(t, p, name) })
+		}
+	}
+	if p, ok := model.(ParameterGetterSetterErr); ok {
+		run("GetAndSetParametersErr", func(t *testing.T) { TestGetAndSetParametersErr(t, p, name, opts...) })
+	}
+	if p, ok := model.(ParameterNamer); ok {
+		run("ParameterNames", func(t *testing.T) { TestParameterNames(t, p, name) })
+	}
+	if p, ok := model.(SingleParameterSetter); ok {
+		run("SetParameter", func(t *testing.T) { TestSetParameter(t, p, name, opts...) })
+	}
+
+	if p, ok := model.(Predictor); ok {
+		run("Predict", func(t *testing.T) { TestPredict(t, p, name, opts...) })
+		if p.OutputDim() > 1 {
+			run("MultiOutput", func(t *testing.T) { TestMultiOutput(t, p, name, opts...) })
+		}
+	}
+	if p, ok := model.(BatchPredictor); ok {
+		run("PredictBatch", func(t *testing.T) {
+			inputs := mat64.NewDense(nProbes, p.InputDim(), nil)
+			for i, probe := range randomProbes(nProbes, p.InputDim(), rnd) {
+				inputs.SetRow(i, probe)
+			}
+			TestPredictBatch(t, p, inputs, name, opts...)
+		})
+	}
+	if p, ok := model.(ParameterPredictor); ok {
+		run("ParameterAliasing", func(t *testing.T) { TestParameterAliasing(t, p, name, opts...) })
+		run("SetParametersCopies", func(t *testing.T) { TestSetParametersCopies(t, p, name, opts...) })
+		run("ParametersAffectPredictions", func(t *testing.T) { TestParametersAffectPredictions(t, p, name, opts...) })
+		run("PredictPreservesParameters", func(t *testing.T) { TestPredictPreservesParameters(t, p, name, opts...) })
+		run("SetParametersIdempotent", func(t *testing.T) { TestSetParametersIdempotent(t, p, name, opts...) })
+	}
+	_, isPred := model.(PredDeriver)
+	_, isParam := model.(ParamDeriver)
+	if isPred || isParam {
+		run("Jacobian", func(t *testing.T) { CheckJacobian(t, model.(Predictor), s.fdTol, name, opts...) })
+	}
+	if tr, ok := model.(TransformDeriver); ok {
+		run("TransformJacobian", func(t *testing.T) { CheckTransformJacobian(t, tr, s.fdTol, name, opts...) })
+	}
+	if c, ok := model.(Cloner); ok {
+		run("Clone", func(t *testing.T) { TestClone(t, c, name, opts...) })
+	}
+
+	if l, ok := model.(Losser); ok {
+		run("LossFunction", func(t *testing.T) { TestLossFunction(t, l, name, opts...) })
+	}
+	if r, ok := model.(Regularizer); ok {
+		run("Regularizer", func(t *testing.T) { TestRegularizer(t, r, name, opts...) })
+	}
+	if a, ok := model.(Activator); ok {
+		run("Activator", func(t *testing.T) { TestActivator(t, a, name, opts...) })
+	}
+	if io, ok := model.(InputOutputer); ok {
+		if f, ok := model.(Featurizer); ok {
+			run("Featurize", func(t *testing.T) { TestFeaturize(t, f, io.InputDim(), name, opts...) })
+		}
+	}
+
+	if !ran {
+		t.Errorf("%v: implements no interface known to regtest", name)
+	}
+}
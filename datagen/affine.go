@@ -0,0 +1,73 @@
+package datagen
+
+import "math/rand"
+
+// Affine is the invertible affine map x ↦ Rotation·(Scale∘x) + Shift, where Rotation
+// is orthogonal and ∘ is the elementwise product.
+type Affine struct {
+	Rotation [][]float64
+	Scale    []float64
+	Shift    []float64
+}
+
+// RandomAffine returns a random affine map on dim-dimensional inputs, for invariance
+// and equivariance tests (for example, ordinary least squares predictions are
+// unchanged when the training and test inputs are transformed by the same map). The
+// rotation is uniformly random, the scales are uniform in [0.5, 2) and the shifts are
+// standard normal.
+func RandomAffine(dim int, rnd *rand.Rand) Affine {
+	a := Affine{
+		Rotation: orthonormalColumns(dim, dim, rnd),
+		Scale:    make([]float64, dim),
+		Shift:    make([]float64, dim),
+	}
+	for i := range a.Scale {
+		a.Scale[i] = uniform(rnd, 0.5, 2)
+		a.Shift[i] = normFloat64(rnd)
+	}
+	return a
+}
+
+// Apply returns the image of x under a
+func (a Affine) Apply(x []float64) []float64 {
+	if len(x) != len(a.Scale) {
+		panic("datagen: input length does not match affine map")
+	}
+	y := make([]float64, len(x))
+	for i, row := range a.Rotation {
+		v := a.Shift[i]
+		for j, r := range row {
+			v += r * a.Scale[j] * x[j]
+		}
+		y[i] = v
+	}
+	return y
+}
+
+// Invert returns the preimage of y under a, so that a.Invert(a.Apply(x)) equals x up
+// to rounding
+func (a Affine) Invert(y []float64) []float64 {
+	if len(y) != len(a.Scale) {
+		panic("datagen: input length does not match affine map")
+	}
+	x := make([]float64, len(y))
+	for i, row := range a.Rotation {
+		d := y[i] - a.Shift[i]
+		for j, r := range row {
+			x[j] += r * d
+		}
+	}
+	for j := range x {
+		x[j] /= a.Scale[j]
+	}
+	return x
+}
+
+// Transform returns the image of each of the inputs under a. inputs is not modified.
+func (a Affine) Transform(inputs [][]float64) [][]float64 {
+	out := make([][]float64, len(inputs))
+	for i, x := range inputs {
+		out[i] = a.Apply(x)
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Blobs generates a classification data set with nClasses isotropic Gaussian blobs in
+// nFeatures dimensions. The class centers are uniform in [-10, 10) in each dimension
+// and are returned with the samples. Samples are assigned to classes in turn, and each
+// is its class center plus Gaussian noise with standard deviation std.
+func Blobs(nSamples, nFeatures, nClasses int, std float64, rnd *rand.Rand) (inputs [][]float64, labels []int, centers [][]float64) {
+	if nClasses < 1 {
+		panic("datagen: need at least one class")
+	}
+	centers = newMatrix(nClasses, nFeatures)
+	for c := range centers {
+		for j := range centers[c] {
+			centers[c][j] = uniform(rnd, -10, 10)
+		}
+	}
+	inputs = newMatrix(nSamples, nFeatures)
+	labels = make([]int, nSamples)
+	for i := range inputs {
+		c := i % nClasses
+		labels[i] = c
+		for j := range inputs[i] {
+			inputs[i][j] = centers[c][j] + std*normFloat64(rnd)
+		}
+	}
+	return inputs, labels, centers
+}
+
+// Moons generates a two-class data set of two interleaving half circles in two
+// dimensions. Class 0 is the upper half of the unit circle, and class 1 is the lower
+// half circle centered at (1, 0.5). An independent draw from noise, which may be nil,
+// is added to each coordinate.
+func Moons(nSamples int, noise Noiser, rnd *rand.Rand) (inputs [][]float64, labels []int) {
+	inputs = newMatrix(nSamples, 2)
+	labels = make([]int, nSamples)
+	for i := range inputs {
+		theta := uniform(rnd, 0, math.Pi)
+		c := i % 2
+		labels[i] = c
+		if c == 0 {
+			inputs[i][0] = math.Cos(theta)
+			inputs[i][1] = math.Sin(theta)
+		} else {
+			inputs[i][0] = 1 - math.Cos(theta)
+			inputs[i][1] = 0.5 - math.Sin(theta)
+		}
+		inputs[i][0] += draw(noise, rnd)
+		inputs[i][1] += draw(noise, rnd)
+	}
+	return inputs, labels
+}
+
+// Circles generates a two-class data set of two concentric circles in two dimensions.
+// Class 0 is the unit circle and class 1 is the circle of radius factor, which must be
+// between zero and one. An independent draw from noise, which may be nil, is added to
+// each coordinate.
+func Circles(nSamples int, factor float64, noise Noiser, rnd *rand.Rand) (inputs [][]float64, labels []int) {
+	if factor <= 0 || factor >= 1 {
+		panic("datagen: circle factor must be between zero and one")
+	}
+	inputs = newMatrix(nSamples, 2)
+	labels = make([]int, nSamples)
+	for i := range inputs {
+		theta := uniform(rnd, 0, 2*math.Pi)
+		c := i % 2
+		labels[i] = c
+		r := 1.0
+		if c == 1 {
+			r = factor
+		}
+		inputs[i][0] = r*math.Cos(theta) + draw(noise, rnd)
+		inputs[i][1] = r*math.Sin(theta) + draw(noise, rnd)
+	}
+	return inputs, labels
+}
+
+// OneHot converts class labels in [0, nClasses) into one-hot encoded outputs, in the
+// form used by regression-style classifiers.
+func OneHot(labels []int, nClasses int) [][]float64 {
+	outputs := newMatrix(len(labels), nClasses)
+	for i, c := range labels {
+		if c < 0 || c >= nClasses {
+			panic("datagen: label out of range")
+		}
+		outputs[i][c] = 1
+	}
+	return outputs
+}
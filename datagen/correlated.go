@@ -0,0 +1,83 @@
+package datagen
+
+import (
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// RandomCovariance returns a random dim×dim symmetric positive definite matrix,
+// A*Aᵀ/dim + 0.01*I where A has standard normal entries.
+func RandomCovariance(dim int, rnd *rand.Rand) *mat64.Dense {
+	a := mat64.NewDense(dim, dim, nil)
+	for i := 0; i < dim; i++ {
+		for j := 0; j < dim; j++ {
+			a.Set(i, j, normFloat64(rnd))
+		}
+	}
+	cov := mat64.NewDense(dim, dim, nil)
+	for i := 0; i < dim; i++ {
+		for j := 0; j <= i; j++ {
+			var v float64
+			for k := 0; k < dim; k++ {
+				v += a.At(i, k) * a.At(j, k)
+			}
+			v /= float64(dim)
+			if i == j {
+				v += 0.01
+			}
+			cov.Set(i, j, v)
+			cov.Set(j, i, v)
+		}
+	}
+	return cov
+}
+
+// MultivariateNormal returns nSamples draws from the zero-mean multivariate Gaussian
+// with covariance cov, which must be symmetric positive definite.
+func MultivariateNormal(nSamples int, cov *mat64.Dense, rnd *rand.Rand) [][]float64 {
+	dim, c := cov.Dims()
+	if dim != c {
+		panic("datagen: covariance matrix not square")
+	}
+	chol := mat64.Cholesky(cov)
+	if !chol.SPD {
+		panic("datagen: covariance matrix not positive definite")
+	}
+	samples := newMatrix(nSamples, dim)
+	z := make([]float64, dim)
+	for i := range samples {
+		for j := range z {
+			z[j] = normFloat64(rnd)
+		}
+		for j := 0; j < dim; j++ {
+			var v float64
+			for k := 0; k <= j; k++ {
+				v += chol.L.At(j, k) * z[k]
+			}
+			samples[i][j] = v
+		}
+	}
+	return samples
+}
+
+// Correlated generates a data set like Linear, except that the inputs are drawn from
+// the zero-mean multivariate Gaussian with covariance cov, for testing regressors and
+// regularizers on multicollinear features. Use RandomCovariance for a random covariance.
+func Correlated(nSamples int, cov *mat64.Dense, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, coefficients []float64) {
+	inputs = MultivariateNormal(nSamples, cov, rnd)
+	dim, _ := cov.Dims()
+	coefficients = make([]float64, dim)
+	for i := range coefficients {
+		coefficients[i] = normFloat64(rnd)
+	}
+	outputs = newMatrix(nSamples, 1)
+	for i, x := range inputs {
+		var y float64
+		for j, v := range x {
+			y += coefficients[j] * v
+		}
+		outputs[i][0] = y + draw(noise, rnd)
+	}
+	return inputs, outputs, coefficients
+}
@@ -0,0 +1,103 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// choose returns the sorted indices of a random fraction of n items
+func choose(n int, fraction float64, rnd *rand.Rand) []int {
+	if fraction < 0 || fraction > 1 {
+		panic("datagen: fraction must be between zero and one")
+	}
+	k := int(math.Floor(fraction*float64(n) + 0.5))
+	idx := perm(rnd, n)[:k]
+	sort.Ints(idx)
+	return idx
+}
+
+// InjectOutliers corrupts the outputs of a random fraction of the samples in place, for
+// testing the robustness of regressors. Every output of a corrupted sample is shifted
+// by magnitude in a random direction. The inputs are not modified. The indices of the
+// corrupted samples are returned in increasing order.
+func InjectOutliers(inputs, outputs [][]float64, fraction, magnitude float64, rnd *rand.Rand) []int {
+	if len(inputs) != len(outputs) {
+		panic("datagen: inputs and outputs have different number of rows")
+	}
+	idx := choose(len(outputs), fraction, rnd)
+	for _, i := range idx {
+		for j := range outputs[i] {
+			if uniform(rnd, 0, 1) < 0.5 {
+				outputs[i][j] -= magnitude
+			} else {
+				outputs[i][j] += magnitude
+			}
+		}
+	}
+	return idx
+}
+
+// Entry is the position of an element in a data set
+type Entry struct {
+	Row, Col int
+}
+
+// InjectMissing sets a random fraction of the entries of inputs to NaN in place, for
+// testing models that claim missing-value support, or that models without support
+// fail cleanly. The positions of the missing entries are returned in row-major order.
+func InjectMissing(inputs [][]float64, fraction float64, rnd *rand.Rand) []Entry {
+	if len(inputs) == 0 {
+		return nil
+	}
+	nCols := len(inputs[0])
+	for _, row := range inputs {
+		if len(row) != nCols {
+			panic("datagen: rows have different lengths")
+		}
+	}
+	idx := choose(len(inputs)*nCols, fraction, rnd)
+	entries := make([]Entry, len(idx))
+	for k, i := range idx {
+		entries[k] = Entry{Row: i / nCols, Col: i % nCols}
+		inputs[i/nCols][i%nCols] = math.NaN()
+	}
+	return entries
+}
+
+// Duplicate returns copies of inputs and outputs with a random fraction of the
+// samples duplicated, for metamorphic tests (duplicating samples must not change an
+// unweighted least squares fit) and for algorithms sensitive to ties. The result is a
+// deep copy with the duplicates appended after the original samples, and the index of
+// the original of each duplicate is returned in increasing order.
+func Duplicate(inputs, outputs [][]float64, fraction float64, rnd *rand.Rand) (dupInputs, dupOutputs [][]float64, source []int) {
+	if len(inputs) != len(outputs) {
+		panic("datagen: inputs and outputs have different number of rows")
+	}
+	source = choose(len(inputs), fraction, rnd)
+	all := make([]int, len(inputs), len(inputs)+len(source))
+	for i := range all {
+		all[i] = i
+	}
+	all = append(all, source...)
+	dupInputs, dupOutputs = subset(inputs, outputs, all)
+	for i := range dupInputs {
+		dupInputs[i] = append([]float64(nil), dupInputs[i]...)
+		dupOutputs[i] = append([]float64(nil), dupOutputs[i]...)
+	}
+	return dupInputs, dupOutputs, source
+}
+
+// AppendConstant returns a copy of inputs with a constant column appended to every
+// row for each element of values, so that tests can check that regressors handle
+// zero-variance features gracefully or fail with a clear error rather than producing
+// NaN coefficients. inputs is not modified.
+func AppendConstant(inputs [][]float64, values ...float64) [][]float64 {
+	out := make([][]float64, len(inputs))
+	for i, row := range inputs {
+		out[i] = make([]float64, len(row), len(row)+len(values))
+		copy(out[i], row)
+		out[i] = append(out[i], values...)
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package datagen
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadCSV reads a numeric data set from a CSV file, such as a fixture in testdata/.
+// The columns listed in targetCols become the outputs, in the order listed, and the
+// remaining columns become the inputs, in file order. If any field of the first record
+// is not a number, the first record is treated as a header and skipped. Every other
+// field must be a number.
+func LoadCSV(path string, targetCols []int) (inputs, outputs [][]float64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+	nCols := len(records[0])
+	isTarget := make([]bool, nCols)
+	for _, c := range targetCols {
+		if c < 0 || c >= nCols {
+			return nil, nil, fmt.Errorf("datagen: target column %v out of range for %v columns", c, nCols)
+		}
+		if isTarget[c] {
+			return nil, nil, fmt.Errorf("datagen: target column %v listed twice", c)
+		}
+		isTarget[c] = true
+	}
+
+	start := 0
+	for _, field := range records[0] {
+		if _, err := parseField(field); err != nil {
+			start = 1
+			break
+		}
+	}
+
+	row := make([]float64, nCols)
+	for r, record := range records[start:] {
+		for c, field := range record {
+			row[c], err = parseField(field)
+			if err != nil {
+				return nil, nil, fmt.Errorf("datagen: %v: record %v column %v: %v", path, r+start+1, c, err)
+			}
+		}
+		in := make([]float64, 0, nCols-len(targetCols))
+		for c, v := range row {
+			if !isTarget[c] {
+				in = append(in, v)
+			}
+		}
+		out := make([]float64, len(targetCols))
+		for k, c := range targetCols {
+			out[k] = row[c]
+		}
+		inputs = append(inputs, in)
+		outputs = append(outputs, out)
+	}
+	return inputs, outputs, nil
+}
+
+func parseField(field string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(field), 64)
+}
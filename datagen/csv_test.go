@@ -0,0 +1,94 @@
+package datagen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile writes contents to a file in a temporary directory and returns its path
+func writeFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCSV(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		contents   string
+		targetCols []int
+		inputs     [][]float64
+		outputs    [][]float64
+	}{
+		{
+			name:       "no header",
+			contents:   "1,2,3\n4,5,6\n",
+			targetCols: []int{2},
+			inputs:     [][]float64{{1, 2}, {4, 5}},
+			outputs:    [][]float64{{3}, {6}},
+		},
+		{
+			name:       "header",
+			contents:   "a,b,y\n1,2,3\n",
+			targetCols: []int{2},
+			inputs:     [][]float64{{1, 2}},
+			outputs:    [][]float64{{3}},
+		},
+		{
+			name:       "targets in listed order",
+			contents:   "1,2,3,4\n",
+			targetCols: []int{3, 0},
+			inputs:     [][]float64{{2, 3}},
+			outputs:    [][]float64{{4, 1}},
+		},
+		{
+			name:       "no targets",
+			contents:   "1, 2\n",
+			targetCols: nil,
+			inputs:     [][]float64{{1, 2}},
+			outputs:    [][]float64{{}},
+		},
+		{
+			name:       "empty",
+			contents:   "",
+			targetCols: []int{0},
+		},
+	} {
+		inputs, outputs, err := LoadCSV(writeFile(t, "data.csv", test.contents), test.targetCols)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(inputs, test.inputs) {
+			t.Errorf("%v: inputs are %v, expected %v", test.name, inputs, test.inputs)
+		}
+		if !reflect.DeepEqual(outputs, test.outputs) {
+			t.Errorf("%v: outputs are %v, expected %v", test.name, outputs, test.outputs)
+		}
+	}
+}
+
+func TestLoadCSVErrors(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		contents   string
+		targetCols []int
+	}{
+		{name: "target out of range", contents: "1,2\n", targetCols: []int{2}},
+		{name: "negative target", contents: "1,2\n", targetCols: []int{-1}},
+		{name: "target listed twice", contents: "1,2\n", targetCols: []int{1, 1}},
+		{name: "non-numeric field", contents: "1,2\n3,x\n", targetCols: []int{1}},
+		{name: "ragged record", contents: "1,2\n3\n", targetCols: []int{1}},
+	} {
+		if _, _, err := LoadCSV(writeFile(t, "data.csv", test.contents), test.targetCols); err == nil {
+			t.Errorf("%v: no error", test.name)
+		}
+	}
+	if _, _, err := LoadCSV(filepath.Join(t.TempDir(), "missing.csv"), nil); err == nil {
+		t.Errorf("missing file: no error")
+	}
+}
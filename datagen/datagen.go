@@ -0,0 +1,118 @@
+// Package datagen generates synthetic data sets for testing regression algorithms.
+// Every generator takes a *rand.Rand so that data sets are reproducible; a nil
+// source uses the global source from math/rand.
+package datagen
+
+import "math/rand"
+
+func normFloat64(rnd *rand.Rand) float64 {
+	if rnd == nil {
+		return rand.NormFloat64()
+	}
+	return rnd.NormFloat64()
+}
+
+func intn(rnd *rand.Rand, n int) int {
+	if rnd == nil {
+		return rand.Intn(n)
+	}
+	return rnd.Intn(n)
+}
+
+func perm(rnd *rand.Rand, n int) []int {
+	if rnd == nil {
+		return rand.Perm(n)
+	}
+	return rnd.Perm(n)
+}
+
+// uniform returns a uniform random number in [min, max)
+func uniform(rnd *rand.Rand, min, max float64) float64 {
+	var u float64
+	if rnd == nil {
+		u = rand.Float64()
+	} else {
+		u = rnd.Float64()
+	}
+	return min + (max-min)*u
+}
+
+// newMatrix allocates an r×c [][]float64
+func newMatrix(r, c int) [][]float64 {
+	m := make([][]float64, r)
+	for i := range m {
+		m[i] = make([]float64, c)
+	}
+	return m
+}
+
+// Linear generates a data set with a single linear output. Each input has nFeatures
+// standard normal features, and the output is the dot product of the input with the
+// true coefficients plus a draw from noise, which may be nil for noiseless outputs.
+// The true coefficients are standard normal. The noiseless target is also returned as
+// a Function, whose domain is the box of inputs within three standard deviations of
+// zero in each feature.
+func Linear(nSamples, nFeatures int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, coefficients []float64, fn Function) {
+	coefficients = make([]float64, nFeatures)
+	for i := range coefficients {
+		coefficients[i] = normFloat64(rnd)
+	}
+	inputs = newMatrix(nSamples, nFeatures)
+	outputs = newMatrix(nSamples, 1)
+	for i := range inputs {
+		var y float64
+		for j := range inputs[i] {
+			inputs[i][j] = normFloat64(rnd)
+			y += coefficients[j] * inputs[i][j]
+		}
+		outputs[i][0] = y + draw(noise, rnd)
+	}
+	return inputs, outputs, coefficients, linearFunction(coefficients)
+}
+
+// linearFunction returns the linear function with the given coefficients on the box
+// within three standard deviations of zero in each feature
+func linearFunction(coefficients []float64) Function {
+	n := len(coefficients)
+	fn := Function{
+		F: func(x []float64) float64 {
+			if len(x) != n {
+				panic("datagen: input length mismatch")
+			}
+			var y float64
+			for j, c := range coefficients {
+				y += c * x[j]
+			}
+			return y
+		},
+		Grad: func(x, grad []float64) {
+			if len(x) != n || len(grad) != n {
+				panic("datagen: input length mismatch")
+			}
+			copy(grad, coefficients)
+		},
+		Lower: make([]float64, n),
+		Upper: make([]float64, n),
+	}
+	for j := 0; j < n; j++ {
+		fn.Lower[j] = -3
+		fn.Upper[j] = 3
+	}
+	return fn
+}
+
+// Heteroskedastic generates a data set like Linear, except that the noise on each
+// sample is a draw from noise scaled by std(x) for its input x, for testing weighted
+// least squares and variance-modeling regressors. noise should have unit scale, such
+// as Gaussian{Std: 1} or StudentT{DoF: 3, Scale: 1}.
+func Heteroskedastic(nSamples, nFeatures int, std func(x []float64) float64, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, coefficients []float64) {
+	inputs, outputs, coefficients, _ = Linear(nSamples, nFeatures, nil, rnd)
+	for i := range inputs {
+		s := std(inputs[i])
+		if s < 0 {
+			panic("datagen: negative noise standard deviation")
+		}
+		outputs[i][0] += s * draw(noise, rnd)
+	}
+	return inputs, outputs, coefficients
+}
@@ -0,0 +1,48 @@
+package datagen
+
+import "github.com/gonum/matrix/mat64"
+
+// Dense returns the rows of m as a *mat64.Dense that does not share memory with m, so
+// that the output of any generator can be used with models written against mat64:
+//
+//	inputs, outputs, _ := datagen.Friedman1(100, 5, datagen.Gaussian{Std: 0.1}, rnd)
+//	x, y := datagen.Dense(inputs), datagen.Dense(outputs)
+//
+// All rows of m must have the same length. Dense returns nil if m has no rows.
+func Dense(m [][]float64) *mat64.Dense {
+	if len(m) == 0 {
+		return nil
+	}
+	c := len(m[0])
+	data := make([]float64, 0, len(m)*c)
+	for _, row := range m {
+		if len(row) != c {
+			panic("datagen: rows have different lengths")
+		}
+		data = append(data, row...)
+	}
+	return mat64.NewDense(len(m), c, data)
+}
+
+// DenseVector returns v as a len(v)×1 *mat64.Dense, for coefficients and time series.
+func DenseVector(v []float64) *mat64.Dense {
+	if len(v) == 0 {
+		return nil
+	}
+	data := make([]float64, len(v))
+	copy(data, v)
+	return mat64.NewDense(len(v), 1, data)
+}
+
+// Rows returns the rows of m as a [][]float64 that does not share memory with m. It
+// is the inverse of Dense, for passing matrices to the helpers in this package.
+func Rows(m mat64.Matrix) [][]float64 {
+	r, c := m.Dims()
+	rows := newMatrix(r, c)
+	for i := range rows {
+		for j := range rows[i] {
+			rows[i][j] = m.At(i, j)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,150 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Friedman1 generates the Friedman #1 benchmark. Inputs have nFeatures >= 5 features
+// uniform in [0, 1), of which only the first five affect the output
+//
+//	f(x) = 10 sin(π x0 x1) + 20 (x2 - 0.5)^2 + 10 x3 + 5 x4,
+//
+// plus a draw from noise, which may be nil. The noiseless target is returned as a
+// Function with its gradient and the domain of the inputs.
+func Friedman1(nSamples, nFeatures int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+	if nFeatures < 5 {
+		panic("datagen: Friedman #1 needs at least five features")
+	}
+	fn = friedman1Function(nFeatures)
+	inputs, outputs = fn.Sample(nSamples, noise, rnd)
+	return inputs, outputs, fn
+}
+
+// friedmanInputs generates the four inputs shared by Friedman #2 and #3 uniformly on
+// the domain of fn
+func friedmanInputs(nSamples int, fn Function, rnd *rand.Rand) [][]float64 {
+	inputs := newMatrix(nSamples, 4)
+	for i := range inputs {
+		for j := range inputs[i] {
+			inputs[i][j] = uniform(rnd, fn.Lower[j], fn.Upper[j])
+		}
+	}
+	return inputs
+}
+
+// Friedman2 generates the Friedman #2 benchmark with four inputs, x0 in [0, 100),
+// x1 in [40π, 560π), x2 in [0, 1) and x3 in [1, 11), and output
+//
+//	f(x) = sqrt(x0^2 + (x1 x2 - 1/(x1 x3))^2),
+//
+// plus a draw from noise, which may be nil. The noiseless target is returned as a
+// Function with its gradient and the domain of the inputs.
+func Friedman2(nSamples int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+	fn = friedman2Function
+	inputs = friedmanInputs(nSamples, fn, rnd)
+	outputs = newMatrix(nSamples, 1)
+	for i := range inputs {
+		outputs[i][0] = fn.F(inputs[i]) + draw(noise, rnd)
+	}
+	return inputs, outputs, fn
+}
+
+// Friedman3 generates the Friedman #3 benchmark with the inputs of Friedman2 and
+// output
+//
+//	f(x) = atan((x1 x2 - 1/(x1 x3)) / x0),
+//
+// plus a draw from noise, which may be nil. The noiseless target is returned as a
+// Function with its gradient and the domain of the inputs.
+func Friedman3(nSamples int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+	fn = friedman3Function
+	inputs = friedmanInputs(nSamples, fn, rnd)
+	outputs = newMatrix(nSamples, 1)
+	for i := range inputs {
+		outputs[i][0] = fn.F(inputs[i]) + draw(noise, rnd)
+	}
+	return inputs, outputs, fn
+}
+
+// friedman1Function returns Friedman #1 on the unit cube of dimension nFeatures
+func friedman1Function(nFeatures int) Function {
+	fn := Function{
+		F:     friedman1,
+		Grad:  friedman1Grad,
+		Lower: make([]float64, nFeatures),
+		Upper: make([]float64, nFeatures),
+	}
+	for j := range fn.Upper {
+		fn.Upper[j] = 1
+	}
+	return fn
+}
+
+var (
+	friedman2Function = Function{
+		F:     friedman2,
+		Grad:  friedman2Grad,
+		Lower: []float64{0, 40 * math.Pi, 0, 1},
+		Upper: []float64{100, 560 * math.Pi, 1, 11},
+	}
+	friedman3Function = Function{
+		F:     friedman3,
+		Grad:  friedman3Grad,
+		Lower: []float64{0, 40 * math.Pi, 0, 1},
+		Upper: []float64{100, 560 * math.Pi, 1, 11},
+	}
+)
+
+func friedman1(x []float64) float64 {
+	return 10*math.Sin(math.Pi*x[0]*x[1]) + 20*(x[2]-0.5)*(x[2]-0.5) + 10*x[3] + 5*x[4]
+}
+
+func friedman1Grad(x, grad []float64) {
+	c := 10 * math.Pi * math.Cos(math.Pi*x[0]*x[1])
+	grad[0] = c * x[1]
+	grad[1] = c * x[0]
+	grad[2] = 40 * (x[2] - 0.5)
+	grad[3] = 10
+	grad[4] = 5
+	for j := 5; j < len(grad); j++ {
+		grad[j] = 0
+	}
+}
+
+// friedmanResidual returns r = x1 x2 - 1/(x1 x3), shared by Friedman #2 and #3, and
+// its derivatives with respect to x1, x2 and x3
+func friedmanResidual(x []float64) (r, d1, d2, d3 float64) {
+	r = x[1]*x[2] - 1/(x[1]*x[3])
+	d1 = x[2] + 1/(x[1]*x[1]*x[3])
+	d2 = x[1]
+	d3 = 1 / (x[1] * x[3] * x[3])
+	return r, d1, d2, d3
+}
+
+func friedman2(x []float64) float64 {
+	return math.Hypot(x[0], x[1]*x[2]-1/(x[1]*x[3]))
+}
+
+func friedman2Grad(x, grad []float64) {
+	r, d1, d2, d3 := friedmanResidual(x)
+	f := math.Hypot(x[0], r)
+	grad[0] = x[0] / f
+	grad[1] = r * d1 / f
+	grad[2] = r * d2 / f
+	grad[3] = r * d3 / f
+}
+
+func friedman3(x []float64) float64 {
+	return math.Atan((x[1]*x[2] - 1/(x[1]*x[3])) / x[0])
+}
+
+func friedman3Grad(x, grad []float64) {
+	r, d1, d2, d3 := friedmanResidual(x)
+	// The derivative of atan(r/x0) is (x0 dr - r dx0) / (x0² + r²)
+	c := x[0] / (x[0]*x[0] + r*r)
+	grad[0] = -r / (x[0]*x[0] + r*r)
+	grad[1] = c * d1
+	grad[2] = c * d2
+	grad[3] = c * d3
+}
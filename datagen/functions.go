@@ -0,0 +1,129 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Function is a known target function on a box-shaped domain, for measuring how well
+// a trained model approximates the truth rather than only how well it fits noisy data.
+// Generators with a known target, such as Linear, Polynomial and the Friedman
+// benchmarks, return it as a Function alongside the data set.
+type Function struct {
+	// F is the function
+	F func(x []float64) float64
+	// Grad stores the gradient of F at x in grad. It is nil if the gradient is not
+	// available.
+	Grad func(x, grad []float64)
+	// Lower and Upper are the bounds of the domain in each dimension, so the input
+	// dimension is len(Lower)
+	Lower, Upper []float64
+}
+
+// Dim returns the input dimension of fn
+func (fn Function) Dim() int {
+	return len(fn.Lower)
+}
+
+// Sample generates nSamples inputs uniform on the domain of fn, with outputs given by
+// fn.F plus a draw from noise, which may be nil.
+func (fn Function) Sample(nSamples int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64) {
+	inputs = newMatrix(nSamples, fn.Dim())
+	outputs = newMatrix(nSamples, 1)
+	for i := range inputs {
+		for j := range inputs[i] {
+			inputs[i][j] = uniform(rnd, fn.Lower[j], fn.Upper[j])
+		}
+		outputs[i][0] = fn.F(inputs[i]) + draw(noise, rnd)
+	}
+	return inputs, outputs
+}
+
+// Grid returns a regular grid over the domain of fn with n >= 2 points in each
+// dimension, including the bounds, for evaluating approximation error. The grid has
+// n^Dim points.
+func (fn Function) Grid(n int) [][]float64 {
+	if n < 2 {
+		panic("datagen: grid needs at least two points per dimension")
+	}
+	dim := fn.Dim()
+	total := 1
+	for j := 0; j < dim; j++ {
+		total *= n
+	}
+	grid := newMatrix(total, dim)
+	for i := range grid {
+		k := i
+		for j := range grid[i] {
+			frac := float64(k%n) / float64(n-1)
+			grid[i][j] = fn.Lower[j] + frac*(fn.Upper[j]-fn.Lower[j])
+			k /= n
+		}
+	}
+	return grid
+}
+
+// functions is the registry of known target functions
+var functions = map[string]Function{
+	"friedman1": friedman1Function(5),
+	"friedman2": friedman2Function,
+	"friedman3": friedman3Function,
+	"sinc": {
+		F: func(x []float64) float64 {
+			if x[0] == 0 {
+				return 1
+			}
+			return math.Sin(x[0]) / x[0]
+		},
+		Grad: func(x, grad []float64) {
+			if x[0] == 0 {
+				grad[0] = 0
+				return
+			}
+			grad[0] = (x[0]*math.Cos(x[0]) - math.Sin(x[0])) / (x[0] * x[0])
+		},
+		Lower: []float64{-10},
+		Upper: []float64{10},
+	},
+	"rosenbrock": {
+		F: func(x []float64) float64 {
+			a, b := 1-x[0], x[1]-x[0]*x[0]
+			return a*a + 100*b*b
+		},
+		Grad: func(x, grad []float64) {
+			b := x[1] - x[0]*x[0]
+			grad[0] = -2*(1-x[0]) - 400*x[0]*b
+			grad[1] = 200 * b
+		},
+		Lower: []float64{-2, -1},
+		Upper: []float64{2, 3},
+	},
+}
+
+// RegisterFunction adds fn to the registry under name, replacing any function already
+// registered with that name, so that downstream packages can share their own targets.
+// It is not safe to call concurrently with the other registry functions.
+func RegisterFunction(name string, fn Function) {
+	if len(fn.Lower) != len(fn.Upper) {
+		panic("datagen: lower and upper bounds have different lengths")
+	}
+	functions[name] = fn
+}
+
+// LookupFunction returns the registered function with the given name, and whether
+// there is one.
+func LookupFunction(name string) (Function, bool) {
+	fn, ok := functions[name]
+	return fn, ok
+}
+
+// FunctionNames returns the names of the registered functions in sorted order
+func FunctionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
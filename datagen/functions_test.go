@@ -0,0 +1,130 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkGrad fails if fn.Grad doesn't match central finite differences of fn.F at
+// random points in the domain of fn
+func checkGrad(t *testing.T, fn Function, rnd *rand.Rand, name string) {
+	if fn.Grad == nil {
+		t.Errorf("%v: no gradient", name)
+		return
+	}
+	const h = 1e-6
+	grad := make([]float64, fn.Dim())
+	x := make([]float64, fn.Dim())
+	for k := 0; k < 10; k++ {
+		for j := range x {
+			// Stay away from the bounds, where some targets are singular
+			x[j] = uniform(rnd, fn.Lower[j], fn.Upper[j])*0.98 + 0.01*(fn.Lower[j]+fn.Upper[j])
+		}
+		fn.Grad(x, grad)
+		for j := range x {
+			orig := x[j]
+			step := h * math.Max(1, math.Abs(orig))
+			x[j] = orig + step
+			f1 := fn.F(x)
+			x[j] = orig - step
+			f2 := fn.F(x)
+			x[j] = orig
+			fd := (f1 - f2) / (2 * step)
+			if math.Abs(fd-grad[j]) > 1e-5*math.Max(1, math.Abs(fd)) {
+				t.Errorf("%v: gradient %v at %v is %v, finite difference %v", name, j, x, grad[j], fd)
+				return
+			}
+		}
+	}
+}
+
+func TestFunctionGrad(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, name := range FunctionNames() {
+		fn, _ := LookupFunction(name)
+		checkGrad(t, fn, rnd, name)
+	}
+	_, _, _, linear := Linear(1, 3, nil, rnd)
+	_, _, friedman1 := Friedman1(1, 7, nil, rnd)
+	_, _, poly := Polynomial(1, 3, 4, nil, rnd)
+	_, _, interaction := Interaction(1, 4, nil, rnd)
+	for name, fn := range map[string]Function{
+		"Linear":      linear,
+		"Friedman1":   friedman1,
+		"Polynomial":  poly,
+		"Interaction": interaction,
+	} {
+		checkGrad(t, fn, rnd, name)
+	}
+}
+
+func TestGeneratorFunctions(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		dim  int
+		gen  func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function)
+	}{
+		{"Linear", 3, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			inputs, outputs, _, fn = Linear(20, 3, nil, rnd)
+			return inputs, outputs, fn
+		}},
+		{"Friedman1", 6, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			return Friedman1(20, 6, nil, rnd)
+		}},
+		{"Friedman2", 4, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			return Friedman2(20, nil, rnd)
+		}},
+		{"Friedman3", 4, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			return Friedman3(20, nil, rnd)
+		}},
+		{"Polynomial", 2, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			return Polynomial(20, 2, 3, nil, rnd)
+		}},
+		{"Interaction", 3, func(rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+			return Interaction(20, 3, nil, rnd)
+		}},
+	} {
+		inputs, outputs, fn := test.gen(rand.New(rand.NewSource(1)))
+		if fn.Dim() != test.dim || len(fn.Upper) != test.dim {
+			t.Errorf("%v: function has dimension %v, expected %v", test.name, fn.Dim(), test.dim)
+			continue
+		}
+		for i, x := range inputs {
+			if y := fn.F(x); y != outputs[i][0] {
+				t.Errorf("%v: noiseless output %v is %v, function is %v", test.name, i, outputs[i][0], y)
+				break
+			}
+		}
+		// Linear inputs are Gaussian, so only the uniform generators stay in the domain
+		if test.name == "Linear" {
+			continue
+		}
+		for i, x := range inputs {
+			for j, v := range x {
+				if v < fn.Lower[j] || v >= fn.Upper[j] {
+					t.Errorf("%v: input [%v][%v] = %v outside the domain", test.name, i, j, v)
+				}
+			}
+		}
+	}
+}
+
+func TestFunctionGrid(t *testing.T) {
+	fn := Function{Lower: []float64{0, -1}, Upper: []float64{1, 1}}
+	grid := fn.Grid(3)
+	if len(grid) != 9 {
+		t.Fatalf("grid has %v points, expected 9", len(grid))
+	}
+	seen := make(map[[2]float64]bool)
+	for _, x := range grid {
+		seen[[2]float64{x[0], x[1]}] = true
+	}
+	for _, x0 := range []float64{0, 0.5, 1} {
+		for _, x1 := range []float64{-1, 0, 1} {
+			if !seen[[2]float64{x0, x1}] {
+				t.Errorf("grid is missing (%v, %v)", x0, x1)
+			}
+		}
+	}
+}
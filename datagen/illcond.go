@@ -0,0 +1,96 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// orthonormalColumns returns a random r×c matrix with orthonormal columns, r >= c,
+// by Gram-Schmidt orthogonalization of a standard normal matrix
+func orthonormalColumns(r, c int, rnd *rand.Rand) [][]float64 {
+	cols := newMatrix(c, r)
+	for j := range cols {
+		for {
+			for i := range cols[j] {
+				cols[j][i] = normFloat64(rnd)
+			}
+			for k := 0; k < j; k++ {
+				var dot float64
+				for i := range cols[j] {
+					dot += cols[j][i] * cols[k][i]
+				}
+				for i := range cols[j] {
+					cols[j][i] -= dot * cols[k][i]
+				}
+			}
+			var norm float64
+			for _, v := range cols[j] {
+				norm += v * v
+			}
+			norm = math.Sqrt(norm)
+			if norm > 1e-8 {
+				for i := range cols[j] {
+					cols[j][i] /= norm
+				}
+				break
+			}
+		}
+	}
+	m := newMatrix(r, c)
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] = cols[j][i]
+		}
+	}
+	return m
+}
+
+// IllConditioned generates a data set whose nSamples×nFeatures design matrix has
+// condition number cond, for testing the numerical stability of linear solvers and
+// regularized models. The inputs are constructed from their singular value
+// decomposition U*S*Vᵀ, with random orthonormal U and V and singular values spaced
+// logarithmically from sqrt(nSamples) down to sqrt(nSamples)/cond. The single output
+// is linear in the inputs, with standard normal coefficients, plus a draw from noise,
+// which may be nil.
+func IllConditioned(nSamples, nFeatures int, cond float64, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, coefficients []float64) {
+	if nSamples < nFeatures {
+		panic("datagen: need at least as many samples as features")
+	}
+	if cond < 1 {
+		panic("datagen: condition number must be at least one")
+	}
+	u := orthonormalColumns(nSamples, nFeatures, rnd)
+	v := orthonormalColumns(nFeatures, nFeatures, rnd)
+	sigma := make([]float64, nFeatures)
+	for k := range sigma {
+		frac := 0.0
+		if nFeatures > 1 {
+			frac = float64(k) / float64(nFeatures-1)
+		}
+		sigma[k] = math.Sqrt(float64(nSamples)) * math.Pow(cond, -frac)
+	}
+
+	inputs = newMatrix(nSamples, nFeatures)
+	for i := range inputs {
+		for j := range inputs[i] {
+			var x float64
+			for k, s := range sigma {
+				x += u[i][k] * s * v[j][k]
+			}
+			inputs[i][j] = x
+		}
+	}
+	coefficients = make([]float64, nFeatures)
+	for j := range coefficients {
+		coefficients[j] = normFloat64(rnd)
+	}
+	outputs = newMatrix(nSamples, 1)
+	for i, x := range inputs {
+		var y float64
+		for j, xj := range x {
+			y += coefficients[j] * xj
+		}
+		outputs[i][0] = y + draw(noise, rnd)
+	}
+	return inputs, outputs, coefficients
+}
@@ -0,0 +1,88 @@
+package datagen
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxLibSVMLine is the longest line LoadLibSVM accepts. Lines of high-dimensional
+// data sets are far longer than the default limit of bufio.Scanner.
+const maxLibSVMLine = 1 << 30
+
+// LoadLibSVM reads a data set in the libsvm/svmlight sparse text format, where each
+// line is
+//
+//	label index:value index:value ...
+//
+// with one-based indices in increasing order. Comments starting with '#' and query ids
+// ("qid:...") are ignored. The inputs are returned both as sparse vectors, with
+// zero-based indices, and densified to the largest index in the file. Each output is
+// the label of its line.
+func LoadLibSVM(path string) (sparse []SparseVector, dense, outputs [][]float64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	nFeatures := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxLibSVMLine)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if i := strings.IndexByte(text, '#'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		label, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("datagen: %v:%v: bad label: %v", path, line, err)
+		}
+		var v SparseVector
+		for _, field := range fields[1:] {
+			if strings.HasPrefix(field, "qid:") {
+				continue
+			}
+			colon := strings.IndexByte(field, ':')
+			if colon < 0 {
+				return nil, nil, nil, fmt.Errorf("datagen: %v:%v: bad feature %q", path, line, field)
+			}
+			idx, err := strconv.Atoi(field[:colon])
+			if err != nil || idx < 1 {
+				return nil, nil, nil, fmt.Errorf("datagen: %v:%v: bad index in %q", path, line, field)
+			}
+			val, err := strconv.ParseFloat(field[colon+1:], 64)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("datagen: %v:%v: bad value in %q", path, line, field)
+			}
+			idx--
+			if n := len(v.Indices); n > 0 && idx <= v.Indices[n-1] {
+				return nil, nil, nil, fmt.Errorf("datagen: %v:%v: indices not increasing", path, line)
+			}
+			v.Indices = append(v.Indices, idx)
+			v.Values = append(v.Values, val)
+			if idx+1 > nFeatures {
+				nFeatures = idx + 1
+			}
+		}
+		sparse = append(sparse, v)
+		outputs = append(outputs, []float64{label})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	dense = make([][]float64, len(sparse))
+	for i, v := range sparse {
+		dense[i] = v.Dense(nFeatures)
+	}
+	return sparse, dense, outputs, nil
+}
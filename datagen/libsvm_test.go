@@ -0,0 +1,93 @@
+package datagen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadLibSVM(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		contents string
+		sparse   []SparseVector
+		dense    [][]float64
+		outputs  [][]float64
+	}{
+		{
+			name:     "basic",
+			contents: "1 1:0.5 3:2\n-1 2:1\n",
+			sparse: []SparseVector{
+				{Indices: []int{0, 2}, Values: []float64{0.5, 2}},
+				{Indices: []int{1}, Values: []float64{1}},
+			},
+			dense:   [][]float64{{0.5, 0, 2}, {0, 1, 0}},
+			outputs: [][]float64{{1}, {-1}},
+		},
+		{
+			name:     "comments, blank lines and query ids",
+			contents: "# header\n\n2 qid:3 1:1 # trailing\n",
+			sparse:   []SparseVector{{Indices: []int{0}, Values: []float64{1}}},
+			dense:    [][]float64{{1}},
+			outputs:  [][]float64{{2}},
+		},
+		{
+			name:     "no features",
+			contents: "3\n",
+			sparse:   []SparseVector{{}},
+			dense:    [][]float64{{}},
+			outputs:  [][]float64{{3}},
+		},
+	} {
+		sparse, dense, outputs, err := LoadLibSVM(writeFile(t, "data.svm", test.contents))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(sparse, test.sparse) {
+			t.Errorf("%v: sparse inputs are %v, expected %v", test.name, sparse, test.sparse)
+		}
+		if !reflect.DeepEqual(dense, test.dense) {
+			t.Errorf("%v: dense inputs are %v, expected %v", test.name, dense, test.dense)
+		}
+		if !reflect.DeepEqual(outputs, test.outputs) {
+			t.Errorf("%v: outputs are %v, expected %v", test.name, outputs, test.outputs)
+		}
+	}
+}
+
+func TestLoadLibSVMLongLine(t *testing.T) {
+	// Longer than the 64 KiB default line limit of bufio.Scanner
+	const nFeatures = 20000
+	fields := []string{"1"}
+	for i := 1; i <= nFeatures; i++ {
+		fields = append(fields, fmt.Sprintf("%v:1.5", i))
+	}
+	sparse, dense, _, err := LoadLibSVM(writeFile(t, "long.svm", strings.Join(fields, " ")+"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sparse) != 1 || len(sparse[0].Indices) != nFeatures || len(dense[0]) != nFeatures {
+		t.Errorf("long line not read in full")
+	}
+}
+
+func TestLoadLibSVMErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		contents string
+	}{
+		{name: "bad label", contents: "x 1:1\n"},
+		{name: "missing colon", contents: "1 1\n"},
+		{name: "zero index", contents: "1 0:1\n"},
+		{name: "bad index", contents: "1 a:1\n"},
+		{name: "bad value", contents: "1 1:x\n"},
+		{name: "indices not increasing", contents: "1 2:1 1:1\n"},
+		{name: "repeated index", contents: "1 2:1 2:1\n"},
+	} {
+		if _, _, _, err := LoadLibSVM(writeFile(t, "data.svm", test.contents)); err == nil {
+			t.Errorf("%v: no error", test.name)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package datagen
+
+import "math/rand"
+
+// ColumnKind is the type of a column of a mixed data set
+type ColumnKind int
+
+const (
+	// Numeric is a continuous column
+	Numeric ColumnKind = iota
+	// Ordinal is a categorical feature encoded as its level, 0, 1, ..., NumLevels-1
+	Ordinal
+	// Indicator is one column of a one-hot encoded categorical feature, which is one
+	// when the feature has level Level and zero otherwise
+	Indicator
+)
+
+// Column describes a column of a mixed data set
+type Column struct {
+	Kind ColumnKind
+	// Feature is the index of the underlying feature. The numeric features come
+	// first, followed by the categorical features.
+	Feature int
+	// NumLevels is the number of levels of a categorical feature
+	NumLevels int
+	// Level is the level indicated by an Indicator column
+	Level int
+}
+
+// Mixed generates a data set with nNumeric standard normal columns followed by
+// nCategorical categorical features, each with nLevels equally likely levels. If oneHot
+// is true each categorical feature is encoded as nLevels Indicator columns, otherwise
+// as a single Ordinal column. The single output is a linear function of the numeric
+// features plus a random effect for the level of each categorical feature, all standard
+// normal, plus a draw from noise, which may be nil. The returned columns describe each
+// column of the inputs.
+func Mixed(nSamples, nNumeric, nCategorical, nLevels int, oneHot bool, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, columns []Column) {
+	if nCategorical > 0 && nLevels < 2 {
+		panic("datagen: categorical features need at least two levels")
+	}
+	for j := 0; j < nNumeric; j++ {
+		columns = append(columns, Column{Kind: Numeric, Feature: j})
+	}
+	for j := 0; j < nCategorical; j++ {
+		f := nNumeric + j
+		if !oneHot {
+			columns = append(columns, Column{Kind: Ordinal, Feature: f, NumLevels: nLevels})
+			continue
+		}
+		for l := 0; l < nLevels; l++ {
+			columns = append(columns, Column{Kind: Indicator, Feature: f, NumLevels: nLevels, Level: l})
+		}
+	}
+
+	coefficients := make([]float64, nNumeric)
+	for j := range coefficients {
+		coefficients[j] = normFloat64(rnd)
+	}
+	effects := newMatrix(nCategorical, nLevels)
+	for j := range effects {
+		for l := range effects[j] {
+			effects[j][l] = normFloat64(rnd)
+		}
+	}
+
+	inputs = newMatrix(nSamples, len(columns))
+	outputs = newMatrix(nSamples, 1)
+	levels := make([]int, nCategorical)
+	for i := range inputs {
+		var y float64
+		for j := 0; j < nNumeric; j++ {
+			inputs[i][j] = normFloat64(rnd)
+			y += coefficients[j] * inputs[i][j]
+		}
+		for j := range levels {
+			levels[j] = intn(rnd, nLevels)
+			y += effects[j][levels[j]]
+		}
+		for c := nNumeric; c < len(columns); c++ {
+			col := columns[c]
+			level := levels[col.Feature-nNumeric]
+			switch col.Kind {
+			case Ordinal:
+				inputs[i][c] = float64(level)
+			case Indicator:
+				if level == col.Level {
+					inputs[i][c] = 1
+				}
+			}
+		}
+		outputs[i][0] = y + draw(noise, rnd)
+	}
+	return inputs, outputs, columns
+}
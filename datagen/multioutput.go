@@ -0,0 +1,59 @@
+package datagen
+
+import (
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// MultiOutput generates a data set with vector-valued outputs. Each input has
+// nFeatures standard normal features, and its nOutputs outputs are f(x), stored by f
+// in y, plus noise drawn from the zero-mean multivariate Gaussian with covariance
+// noiseCov. Off-diagonal entries of noiseCov correlate the noise across outputs. A
+// nil noiseCov gives noiseless outputs.
+func MultiOutput(nSamples, nFeatures, nOutputs int, f func(x, y []float64), noiseCov *mat64.Dense, rnd *rand.Rand) (inputs, outputs [][]float64) {
+	inputs = newMatrix(nSamples, nFeatures)
+	outputs = newMatrix(nSamples, nOutputs)
+	for i := range inputs {
+		for j := range inputs[i] {
+			inputs[i][j] = normFloat64(rnd)
+		}
+		f(inputs[i], outputs[i])
+	}
+	if noiseCov == nil {
+		return inputs, outputs
+	}
+	if r, _ := noiseCov.Dims(); r != nOutputs {
+		panic("datagen: noise covariance does not match the number of outputs")
+	}
+	noise := MultivariateNormal(nSamples, noiseCov, rnd)
+	for i := range outputs {
+		for j := range outputs[i] {
+			outputs[i][j] += noise[i][j]
+		}
+	}
+	return inputs, outputs
+}
+
+// MultiLinear generates a data set with MultiOutput where the outputs are the linear
+// map y = W x, and returns the true nOutputs×nFeatures weights W, which are standard
+// normal.
+func MultiLinear(nSamples, nFeatures, nOutputs int, noiseCov *mat64.Dense, rnd *rand.Rand) (inputs, outputs [][]float64, weights *mat64.Dense) {
+	weights = mat64.NewDense(nOutputs, nFeatures, nil)
+	for i := 0; i < nOutputs; i++ {
+		for j := 0; j < nFeatures; j++ {
+			weights.Set(i, j, normFloat64(rnd))
+		}
+	}
+	f := func(x, y []float64) {
+		for i := range y {
+			var v float64
+			for j, xj := range x {
+				v += weights.At(i, j) * xj
+			}
+			y[i] = v
+		}
+	}
+	inputs, outputs = MultiOutput(nSamples, nFeatures, nOutputs, f, noiseCov, rnd)
+	return inputs, outputs, weights
+}
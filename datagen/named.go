@@ -0,0 +1,69 @@
+package datagen
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+// named holds the reference data sets. Each is generated from a fixed seed, so its
+// contents are identical across runs and across packages.
+var named = map[string]func(rnd *rand.Rand) (inputs, outputs [][]float64){
+	"linear": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _, _ = Linear(200, 5, Gaussian{Std: 0.1}, rnd)
+		return inputs, outputs
+	},
+	"friedman1": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = Friedman1(200, 10, Gaussian{Std: 1}, rnd)
+		return inputs, outputs
+	},
+	"friedman2": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = Friedman2(200, Gaussian{Std: 10}, rnd)
+		return inputs, outputs
+	},
+	"friedman3": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = Friedman3(200, Gaussian{Std: 0.1}, rnd)
+		return inputs, outputs
+	},
+	"polynomial": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = Polynomial(200, 2, 3, Gaussian{Std: 0.1}, rnd)
+		return inputs, outputs
+	},
+	"illconditioned": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = IllConditioned(200, 5, 1e6, Gaussian{Std: 0.01}, rnd)
+		return inputs, outputs
+	},
+	"mixed": func(rnd *rand.Rand) (inputs, outputs [][]float64) {
+		inputs, outputs, _ = Mixed(200, 3, 2, 4, true, Gaussian{Std: 0.1}, rnd)
+		return inputs, outputs
+	},
+}
+
+// namedSeed is the seed from which every reference data set is generated
+const namedSeed = 1
+
+// ErrUnknownDataset is returned by Named for a name it does not recognize
+var ErrUnknownDataset = errors.New("datagen: unknown data set")
+
+// Named returns the reference data set with the given name, so that tests in
+// different packages can share canonical data. The data sets are deterministic
+// synthetic stand-ins rather than real data, and a fresh copy is returned on every
+// call, so callers may modify it freely. Names lists the available data sets.
+func Named(name string) (inputs, outputs [][]float64, err error) {
+	gen, ok := named[name]
+	if !ok {
+		return nil, nil, ErrUnknownDataset
+	}
+	inputs, outputs = gen(rand.New(rand.NewSource(namedSeed)))
+	return inputs, outputs, nil
+}
+
+// Names returns the names of the reference data sets in sorted order
+func Names() []string {
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,94 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Noiser is a zero-centered noise distribution. Every generator takes a Noiser for
+// the noise it adds, so that robust losses can be tested on heavy-tailed or skewed
+// noise; a nil Noiser adds no noise. AddNoise adds noise to an existing data set.
+type Noiser interface {
+	// Noise returns a draw from the distribution using rnd, which may be nil to use
+	// the global source
+	Noise(rnd *rand.Rand) float64
+}
+
+// Gaussian is normal noise with standard deviation Std
+type Gaussian struct {
+	Std float64
+}
+
+// Noise implements Noiser
+func (g Gaussian) Noise(rnd *rand.Rand) float64 {
+	return g.Std * normFloat64(rnd)
+}
+
+// Laplace is double exponential noise with scale Scale, and so standard deviation
+// √2·Scale
+type Laplace struct {
+	Scale float64
+}
+
+// Noise implements Noiser
+func (l Laplace) Noise(rnd *rand.Rand) float64 {
+	u := uniform(rnd, -0.5, 0.5)
+	if u < 0 {
+		return l.Scale * math.Log(1+2*u)
+	}
+	return -l.Scale * math.Log(1-2*u)
+}
+
+// StudentT is Student's t noise with DoF degrees of freedom scaled by Scale. Small
+// degrees of freedom give heavy tails; the variance is infinite for DoF <= 2.
+type StudentT struct {
+	DoF   int
+	Scale float64
+}
+
+// Noise implements Noiser
+func (s StudentT) Noise(rnd *rand.Rand) float64 {
+	if s.DoF < 1 {
+		panic("datagen: Student's t needs at least one degree of freedom")
+	}
+	var chi2 float64
+	for i := 0; i < s.DoF; i++ {
+		z := normFloat64(rnd)
+		chi2 += z * z
+	}
+	return s.Scale * normFloat64(rnd) / math.Sqrt(chi2/float64(s.DoF))
+}
+
+// Asymmetric is skewed noise that draws from Noiser and multiplies negative draws by
+// Left and positive draws by Right. If Noiser is symmetric the median stays zero, but
+// the mean does not unless Left equals Right.
+type Asymmetric struct {
+	Noiser
+	Left, Right float64
+}
+
+// Noise implements Noiser
+func (a Asymmetric) Noise(rnd *rand.Rand) float64 {
+	e := a.Noiser.Noise(rnd)
+	if e < 0 {
+		return a.Left * e
+	}
+	return a.Right * e
+}
+
+// draw returns a draw from n using rnd, or zero if n is nil
+func draw(n Noiser, rnd *rand.Rand) float64 {
+	if n == nil {
+		return 0
+	}
+	return n.Noise(rnd)
+}
+
+// AddNoise adds an independent draw from n to every element of outputs in place.
+func AddNoise(outputs [][]float64, n Noiser, rnd *rand.Rand) {
+	for i := range outputs {
+		for j := range outputs[i] {
+			outputs[i][j] += n.Noise(rnd)
+		}
+	}
+}
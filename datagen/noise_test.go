@@ -0,0 +1,99 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// constant is a Noiser that always returns the same value, and so draws nothing from
+// the source
+type constant float64
+
+func (c constant) Noise(rnd *rand.Rand) float64 {
+	return float64(c)
+}
+
+func TestGeneratorsNoise(t *testing.T) {
+	cov := RandomCovariance(3, rand.New(rand.NewSource(1)))
+	fn, _ := LookupFunction("rosenbrock")
+	for _, test := range []struct {
+		name  string
+		scale float64
+		gen   func(noise Noiser, rnd *rand.Rand) [][]float64
+	}{
+		{"Linear", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _, _ := Linear(10, 3, noise, rnd)
+			return outputs
+		}},
+		{"Heteroskedastic", 2, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Heteroskedastic(10, 3, func(x []float64) float64 { return 2 }, noise, rnd)
+			return outputs
+		}},
+		{"Polynomial", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Polynomial(10, 2, 3, noise, rnd)
+			return outputs
+		}},
+		{"Interaction", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Interaction(10, 3, noise, rnd)
+			return outputs
+		}},
+		{"Friedman1", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Friedman1(10, 6, noise, rnd)
+			return outputs
+		}},
+		{"Friedman2", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Friedman2(10, noise, rnd)
+			return outputs
+		}},
+		{"Friedman3", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Friedman3(10, noise, rnd)
+			return outputs
+		}},
+		{"Correlated", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Correlated(10, cov, noise, rnd)
+			return outputs
+		}},
+		{"IllConditioned", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := IllConditioned(10, 3, 100, noise, rnd)
+			return outputs
+		}},
+		{"Sparse", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, _, outputs, _ := Sparse(10, 5, 0.4, noise, rnd)
+			return outputs
+		}},
+		{"Mixed", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs, _ := Mixed(10, 2, 2, 3, true, noise, rnd)
+			return outputs
+		}},
+		{"Function.Sample", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, outputs := fn.Sample(10, noise, rnd)
+			return outputs
+		}},
+		{"LinearSampler", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			_, output := LinearSampler([]float64{1, -2}, noise)(rnd)
+			return [][]float64{output}
+		}},
+		{"TrendSeasonal", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			return [][]float64{TrendSeasonal(10, 0.5, 2, 4, noise, rnd)}
+		}},
+		{"Moons", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			inputs, _ := Moons(10, noise, rnd)
+			return inputs
+		}},
+		{"Circles", 1, func(noise Noiser, rnd *rand.Rand) [][]float64 {
+			inputs, _ := Circles(10, 0.5, noise, rnd)
+			return inputs
+		}},
+	} {
+		clean := test.gen(nil, rand.New(rand.NewSource(1)))
+		noisy := test.gen(constant(0.25), rand.New(rand.NewSource(1)))
+		for i := range clean {
+			for j := range clean[i] {
+				if got := noisy[i][j] - clean[i][j]; math.Abs(got-0.25*test.scale) > 1e-12 {
+					t.Errorf("%v: element [%v][%v] shifted by %v, expected %v", test.name, i, j, got, 0.25*test.scale)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// cube returns a Function with f and grad on [-1, 1]^nFeatures, the domain of the
+// polynomial generators
+func cube(nFeatures int, f func(x []float64) float64, grad func(x, grad []float64)) Function {
+	fn := Function{
+		F:     f,
+		Grad:  grad,
+		Lower: make([]float64, nFeatures),
+		Upper: make([]float64, nFeatures),
+	}
+	for j := 0; j < nFeatures; j++ {
+		fn.Lower[j] = -1
+		fn.Upper[j] = 1
+	}
+	return fn
+}
+
+// Polynomial generates a data set whose single output is an additive polynomial of
+// the given degree in each of the nFeatures inputs,
+//
+//	f(x) = sum_j sum_{d=1}^{degree} c_jd x_j^d,
+//
+// with standard normal coefficients, plus a draw from noise, which may be nil. Inputs
+// are uniform in [-1, 1). The noiseless target is returned as a Function with its
+// gradient and the domain of the inputs, so that approximation error can be measured.
+func Polynomial(nSamples, nFeatures, degree int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+	if degree < 1 {
+		panic("datagen: polynomial degree must be positive")
+	}
+	coefficients := newMatrix(nFeatures, degree)
+	for j := range coefficients {
+		for d := range coefficients[j] {
+			coefficients[j][d] = normFloat64(rnd)
+		}
+	}
+	f := func(x []float64) float64 {
+		if len(x) != nFeatures {
+			panic("datagen: input length mismatch")
+		}
+		var y float64
+		for j, v := range x {
+			for d, c := range coefficients[j] {
+				y += c * math.Pow(v, float64(d+1))
+			}
+		}
+		return y
+	}
+	grad := func(x, grad []float64) {
+		if len(x) != nFeatures || len(grad) != nFeatures {
+			panic("datagen: input length mismatch")
+		}
+		for j, v := range x {
+			grad[j] = 0
+			for d, c := range coefficients[j] {
+				grad[j] += c * float64(d+1) * math.Pow(v, float64(d))
+			}
+		}
+	}
+	fn = cube(nFeatures, f, grad)
+	inputs, outputs = fn.Sample(nSamples, noise, rnd)
+	return inputs, outputs, fn
+}
+
+// Interaction generates a data set whose single output has linear terms and all
+// pairwise interaction terms,
+//
+//	f(x) = sum_j a_j x_j + sum_{j<k} b_jk x_j x_k,
+//
+// with standard normal coefficients, plus a draw from noise, which may be nil. Inputs
+// are uniform in [-1, 1). The noiseless target is returned as a Function with its
+// gradient and the domain of the inputs.
+func Interaction(nSamples, nFeatures int, noise Noiser, rnd *rand.Rand) (inputs, outputs [][]float64, fn Function) {
+	linear := make([]float64, nFeatures)
+	for j := range linear {
+		linear[j] = normFloat64(rnd)
+	}
+	pairs := newMatrix(nFeatures, nFeatures)
+	for j := range pairs {
+		for k := j + 1; k < nFeatures; k++ {
+			pairs[j][k] = normFloat64(rnd)
+		}
+	}
+	f := func(x []float64) float64 {
+		if len(x) != nFeatures {
+			panic("datagen: input length mismatch")
+		}
+		var y float64
+		for j, v := range x {
+			y += linear[j] * v
+			for k := j + 1; k < nFeatures; k++ {
+				y += pairs[j][k] * v * x[k]
+			}
+		}
+		return y
+	}
+	grad := func(x, grad []float64) {
+		if len(x) != nFeatures || len(grad) != nFeatures {
+			panic("datagen: input length mismatch")
+		}
+		copy(grad, linear)
+		for j, v := range x {
+			for k := j + 1; k < nFeatures; k++ {
+				grad[j] += pairs[j][k] * x[k]
+				grad[k] += pairs[j][k] * v
+			}
+		}
+	}
+	fn = cube(nFeatures, f, grad)
+	inputs, outputs = fn.Sample(nSamples, noise, rnd)
+	return inputs, outputs, fn
+}
@@ -0,0 +1,88 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// burnIn is the number of initial values discarded per AR coefficient so that
+// generated series are close to stationary
+const burnIn = 100
+
+// AR generates n values of a stationary autoregressive process of order p,
+//
+//	x_t = sum_{k=1}^p phi_k x_{t-k} + e_t,
+//
+// where e_t is a draw from noise. The coefficients phi are random, constructed from
+// partial autocorrelations uniform in (-0.9, 0.9) so that the process is stationary,
+// and are returned with the series.
+func AR(n, p int, noise Noiser, rnd *rand.Rand) (series, phi []float64) {
+	phi = make([]float64, p)
+	prev := make([]float64, p)
+	for k := 0; k < p; k++ {
+		r := uniform(rnd, -0.9, 0.9)
+		copy(prev, phi)
+		phi[k] = r
+		for j := 0; j < k; j++ {
+			phi[j] = prev[j] - r*prev[k-1-j]
+		}
+	}
+	return ARSeries(n, phi, noise, rnd), phi
+}
+
+// ARSeries generates n values of the autoregressive process with coefficients phi
+// and noise e_t drawn from noise, as described by AR.
+func ARSeries(n int, phi []float64, noise Noiser, rnd *rand.Rand) []float64 {
+	burn := burnIn * len(phi)
+	x := make([]float64, n+burn)
+	for t := range x {
+		v := draw(noise, rnd)
+		for k, c := range phi {
+			if t-k-1 >= 0 {
+				v += c * x[t-k-1]
+			}
+		}
+		x[t] = v
+	}
+	return x[burn:]
+}
+
+// TrendSeasonal generates n values of a linear trend plus a sinusoidal seasonal
+// component plus a draw from noise, which may be nil,
+//
+//	x_t = trend*t + amplitude*sin(2πt/period) + e_t.
+//
+// A period of zero gives no seasonal component.
+func TrendSeasonal(n int, trend, amplitude float64, period int, noise Noiser, rnd *rand.Rand) []float64 {
+	if period < 0 {
+		panic("datagen: negative period")
+	}
+	x := make([]float64, n)
+	for t := range x {
+		x[t] = trend * float64(t)
+		if period > 0 {
+			x[t] += amplitude * math.Sin(2*math.Pi*float64(t)/float64(period))
+		}
+		x[t] += draw(noise, rnd)
+	}
+	return x
+}
+
+// Lag converts a series into a regression data set for forecasting, where the input
+// of each sample is the p previous values [x_{t-1}, ..., x_{t-p}] and the output is x_t.
+func Lag(series []float64, p int) (inputs, outputs [][]float64) {
+	if p < 1 || p >= len(series) {
+		panic("datagen: bad lag")
+	}
+	n := len(series) - p
+	inputs = newMatrix(n, p)
+	outputs = newMatrix(n, 1)
+	for i := range inputs {
+		t := i + p
+		for k := range inputs[i] {
+			inputs[i][k] = series[t-k-1]
+		}
+		outputs[i][0] = series[t]
+	}
+	return inputs, outputs
+}
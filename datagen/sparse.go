@@ -0,0 +1,47 @@
+package datagen
+
+import "math/rand"
+
+// SparseVector is a vector given by the indices and values of its nonzero entries.
+// Indices are in increasing order.
+type SparseVector struct {
+	Indices []int
+	Values  []float64
+}
+
+// Dense returns the dense expansion of v with length n
+func (v SparseVector) Dense(n int) []float64 {
+	x := make([]float64, n)
+	for k, i := range v.Indices {
+		x[i] = v.Values[k]
+	}
+	return x
+}
+
+// Sparse generates a data set with nFeatures-dimensional inputs of which a fraction
+// density of the entries in each row are nonzero and standard normal. The inputs are
+// returned both densely (with explicit zeros) and sparsely. The single output is linear
+// in the inputs, with standard normal coefficients, plus a draw from noise, which may
+// be nil.
+func Sparse(nSamples, nFeatures int, density float64, noise Noiser, rnd *rand.Rand) (dense [][]float64, sparse []SparseVector, outputs [][]float64, coefficients []float64) {
+	coefficients = make([]float64, nFeatures)
+	for j := range coefficients {
+		coefficients[j] = normFloat64(rnd)
+	}
+	dense = newMatrix(nSamples, nFeatures)
+	sparse = make([]SparseVector, nSamples)
+	outputs = newMatrix(nSamples, 1)
+	for i := range dense {
+		idx := choose(nFeatures, density, rnd)
+		values := make([]float64, len(idx))
+		var y float64
+		for k, j := range idx {
+			values[k] = normFloat64(rnd)
+			dense[i][j] = values[k]
+			y += coefficients[j] * values[k]
+		}
+		sparse[i] = SparseVector{Indices: idx, Values: values}
+		outputs[i][0] = y + draw(noise, rnd)
+	}
+	return dense, sparse, outputs, coefficients
+}
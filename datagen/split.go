@@ -0,0 +1,176 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Split randomly splits a data set into a training and a test set, with a fraction
+// testFrac (rounded to the nearest sample) going to the test set. The split depends
+// only on the state of rnd. The rows of the returned sets share memory with the rows
+// of inputs and outputs.
+func Split(inputs, outputs [][]float64, testFrac float64, rnd *rand.Rand) (trainIn, trainOut, testIn, testOut [][]float64) {
+	if len(inputs) != len(outputs) {
+		panic("datagen: inputs and outputs have different number of rows")
+	}
+	if testFrac < 0 || testFrac > 1 {
+		panic("datagen: test fraction must be between zero and one")
+	}
+	n := len(inputs)
+	nTest := int(math.Floor(testFrac*float64(n) + 0.5))
+	idx := perm(rnd, n)
+	testIn, testOut = subset(inputs, outputs, idx[:nTest])
+	trainIn, trainOut = subset(inputs, outputs, idx[nTest:])
+	return trainIn, trainOut, testIn, testOut
+}
+
+// subset returns the rows of inputs and outputs at idx
+func subset(inputs, outputs [][]float64, idx []int) (in, out [][]float64) {
+	in = make([][]float64, len(idx))
+	out = make([][]float64, len(idx))
+	for k, i := range idx {
+		in[k] = inputs[i]
+		out[k] = outputs[i]
+	}
+	return in, out
+}
+
+// KFold randomly partitions the indices 0, ..., n-1 into k folds whose sizes differ by
+// at most one. Each fold is the test set of one round of cross-validation. The folds
+// have no spare capacity, so appending to one does not overwrite another.
+func KFold(n, k int, rnd *rand.Rand) [][]int {
+	if k < 2 || k > n {
+		panic("datagen: number of folds must be between two and the number of samples")
+	}
+	idx := perm(rnd, n)
+	folds := make([][]int, k)
+	start := 0
+	for f := range folds {
+		size := n / k
+		if f < n%k {
+			size++
+		}
+		folds[f] = idx[start : start+size : start+size]
+		start += size
+	}
+	return folds
+}
+
+// CrossValidate runs one round of training and prediction per fold and returns the
+// out-of-fold predictions, where row i is the prediction for sample i made by the
+// model trained without the fold containing i. fitPredict must train a fresh model on
+// the training set and return its predictions on testIn.
+func CrossValidate(inputs, outputs [][]float64, folds [][]int, fitPredict func(trainIn, trainOut, testIn [][]float64) ([][]float64, error)) ([][]float64, error) {
+	if len(inputs) != len(outputs) {
+		panic("datagen: inputs and outputs have different number of rows")
+	}
+	n := len(inputs)
+	predictions := make([][]float64, n)
+	inFold := make([]bool, n)
+	for _, fold := range folds {
+		for i := range inFold {
+			inFold[i] = false
+		}
+		for _, i := range fold {
+			inFold[i] = true
+		}
+		train := make([]int, 0, n-len(fold))
+		for i, in := range inFold {
+			if !in {
+				train = append(train, i)
+			}
+		}
+		trainIn, trainOut := subset(inputs, outputs, train)
+		testIn, _ := subset(inputs, outputs, fold)
+		pred, err := fitPredict(trainIn, trainOut, testIn)
+		if err != nil {
+			return nil, err
+		}
+		if len(pred) != len(fold) {
+			panic("datagen: wrong number of predictions for fold")
+		}
+		for k, i := range fold {
+			predictions[i] = pred[k]
+		}
+	}
+	return predictions, nil
+}
+
+// Bootstrap draws n indices from 0, ..., n-1 uniformly with replacement, and returns
+// them along with the sorted out-of-bag indices that were never drawn.
+func Bootstrap(n int, rnd *rand.Rand) (sample, outOfBag []int) {
+	sample = make([]int, n)
+	drawn := make([]bool, n)
+	for i := range sample {
+		sample[i] = intn(rnd, n)
+		drawn[sample[i]] = true
+	}
+	for i, d := range drawn {
+		if !d {
+			outOfBag = append(outOfBag, i)
+		}
+	}
+	return sample, outOfBag
+}
+
+// StratifiedKFold randomly partitions the indices of labels into k folds such that the
+// number of samples of each class differs by at most one between folds, preserving the
+// class proportions of imbalanced data sets. Fold sizes also differ by at most one.
+func StratifiedKFold(labels []int, k int, rnd *rand.Rand) [][]int {
+	if k < 2 || k > len(labels) {
+		panic("datagen: number of folds must be between two and the number of samples")
+	}
+	byClass := make(map[int][]int)
+	var classes []int
+	for i, c := range labels {
+		if _, ok := byClass[c]; !ok {
+			classes = append(classes, c)
+		}
+		byClass[c] = append(byClass[c], i)
+	}
+	sort.Ints(classes)
+
+	folds := make([][]int, k)
+	f := 0
+	for _, c := range classes {
+		idx := byClass[c]
+		for _, j := range perm(rnd, len(idx)) {
+			folds[f] = append(folds[f], idx[j])
+			f = (f + 1) % k
+		}
+	}
+	return folds
+}
+
+// Batches returns one epoch of mini-batches of the indices 0, ..., n-1, each of
+// batchSize indices except possibly the last, which is dropped if it is short and
+// dropLast is true. If shuffle is true the indices are in a random order, and calling
+// Batches again with the same rnd gives the next epoch; otherwise they are in
+// increasing order and rnd is unused.
+func Batches(n, batchSize int, shuffle, dropLast bool, rnd *rand.Rand) [][]int {
+	if batchSize < 1 {
+		panic("datagen: batch size must be positive")
+	}
+	var idx []int
+	if shuffle {
+		idx = perm(rnd, n)
+	} else {
+		idx = make([]int, n)
+		for i := range idx {
+			idx[i] = i
+		}
+	}
+	var batches [][]int
+	for start := 0; start < n; start += batchSize {
+		end := start + batchSize
+		if end > n {
+			if dropLast {
+				break
+			}
+			end = n
+		}
+		batches = append(batches, idx[start:end:end])
+	}
+	return batches
+}
@@ -0,0 +1,312 @@
+package datagen
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// checkPartition fails if the index sets don't partition 0, ..., n-1
+func checkPartition(t *testing.T, sets [][]int, n int, name string) {
+	var all []int
+	for _, set := range sets {
+		all = append(all, set...)
+	}
+	sort.Ints(all)
+	if len(all) != n {
+		t.Errorf("%v: %v indices in total, expected %v", name, len(all), n)
+		return
+	}
+	for i, v := range all {
+		if v != i {
+			t.Errorf("%v: index %v missing or repeated", name, i)
+			return
+		}
+	}
+}
+
+// rowIndex returns the index of the row of rows that is the same slice as row
+func rowIndex(rows [][]float64, row []float64) int {
+	for i := range rows {
+		if &rows[i][0] == &row[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		n         int
+		testFrac  float64
+		nTest     int
+		nTraining int
+	}{
+		{n: 10, testFrac: 0, nTest: 0, nTraining: 10},
+		{n: 10, testFrac: 1, nTest: 10, nTraining: 0},
+		{n: 10, testFrac: 0.25, nTest: 3, nTraining: 7},
+		{n: 7, testFrac: 0.5, nTest: 4, nTraining: 3},
+		{n: 1, testFrac: 0.4, nTest: 0, nTraining: 1},
+	} {
+		inputs := make([][]float64, test.n)
+		outputs := make([][]float64, test.n)
+		for i := range inputs {
+			inputs[i] = []float64{float64(i)}
+			outputs[i] = []float64{-float64(i)}
+		}
+		trainIn, trainOut, testIn, testOut := Split(inputs, outputs, test.testFrac, rand.New(rand.NewSource(1)))
+		if len(testIn) != test.nTest || len(testOut) != test.nTest {
+			t.Errorf("n = %v, testFrac = %v: %v test samples, expected %v", test.n, test.testFrac, len(testIn), test.nTest)
+			continue
+		}
+		if len(trainIn) != test.nTraining || len(trainOut) != test.nTraining {
+			t.Errorf("n = %v, testFrac = %v: %v training samples, expected %v", test.n, test.testFrac, len(trainIn), test.nTraining)
+			continue
+		}
+		var sets [][]int
+		for _, set := range [][][]float64{trainIn, testIn} {
+			var idx []int
+			for _, row := range set {
+				idx = append(idx, rowIndex(inputs, row))
+			}
+			sets = append(sets, idx)
+		}
+		checkPartition(t, sets, test.n, "Split")
+		for k, set := range []struct{ in, out [][]float64 }{{trainIn, trainOut}, {testIn, testOut}} {
+			for j := range set.in {
+				if i := sets[k][j]; &set.out[j][0] != &outputs[i][0] {
+					t.Errorf("n = %v, testFrac = %v: input and output of sample %v separated", test.n, test.testFrac, i)
+				}
+			}
+		}
+	}
+}
+
+func TestSplitSeeded(t *testing.T) {
+	inputs := make([][]float64, 20)
+	for i := range inputs {
+		inputs[i] = []float64{float64(i)}
+	}
+	_, _, a, _ := Split(inputs, inputs, 0.3, rand.New(rand.NewSource(7)))
+	_, _, b, _ := Split(inputs, inputs, 0.3, rand.New(rand.NewSource(7)))
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Split with the same seed gave different test sets: %v and %v", a, b)
+	}
+}
+
+func TestSplitPanics(t *testing.T) {
+	for _, test := range []struct {
+		name            string
+		inputs, outputs [][]float64
+		testFrac        float64
+	}{
+		{name: "mismatched rows", inputs: make([][]float64, 2), outputs: make([][]float64, 3), testFrac: 0.5},
+		{name: "negative fraction", inputs: make([][]float64, 2), outputs: make([][]float64, 2), testFrac: -0.1},
+		{name: "fraction above one", inputs: make([][]float64, 2), outputs: make([][]float64, 2), testFrac: 1.1},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Split did not panic with %v", test.name)
+				}
+			}()
+			Split(test.inputs, test.outputs, test.testFrac, rand.New(rand.NewSource(1)))
+		}()
+	}
+}
+
+func TestKFold(t *testing.T) {
+	for _, test := range []struct {
+		n, k int
+	}{
+		{n: 10, k: 2},
+		{n: 10, k: 3},
+		{n: 10, k: 10},
+		{n: 23, k: 5},
+	} {
+		folds := KFold(test.n, test.k, rand.New(rand.NewSource(1)))
+		if len(folds) != test.k {
+			t.Errorf("n = %v, k = %v: %v folds", test.n, test.k, len(folds))
+			continue
+		}
+		checkPartition(t, folds, test.n, "KFold")
+		for _, fold := range folds {
+			if size := len(fold); size != test.n/test.k && size != test.n/test.k+1 {
+				t.Errorf("n = %v, k = %v: fold of size %v", test.n, test.k, size)
+			}
+		}
+	}
+}
+
+func TestKFoldAppend(t *testing.T) {
+	folds := KFold(9, 3, rand.New(rand.NewSource(1)))
+	second := append([]int(nil), folds[1]...)
+	_ = append(folds[0], -1)
+	if !reflect.DeepEqual(folds[1], second) {
+		t.Errorf("appending to a fold changed the next fold from %v to %v", second, folds[1])
+	}
+}
+
+func TestKFoldPanics(t *testing.T) {
+	for _, k := range []int{1, 11} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("KFold did not panic with k = %v for 10 samples", k)
+				}
+			}()
+			KFold(10, k, rand.New(rand.NewSource(1)))
+		}()
+	}
+}
+
+func TestCrossValidate(t *testing.T) {
+	n := 12
+	inputs := make([][]float64, n)
+	outputs := make([][]float64, n)
+	for i := range inputs {
+		inputs[i] = []float64{float64(i)}
+		outputs[i] = []float64{float64(i * i)}
+	}
+	folds := KFold(n, 4, rand.New(rand.NewSource(1)))
+	// Predict the number of training samples and echo the input, so that each
+	// prediction shows which fold it came from
+	predictions, err := CrossValidate(inputs, outputs, folds, func(trainIn, trainOut, testIn [][]float64) ([][]float64, error) {
+		pred := make([][]float64, len(testIn))
+		for i, in := range testIn {
+			pred[i] = []float64{float64(len(trainIn)), in[0]}
+		}
+		return pred, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, pred := range predictions {
+		if pred[0] != float64(n-n/4) || pred[1] != float64(i) {
+			t.Errorf("prediction %v is %v, expected [%v %v]", i, pred, n-n/4, i)
+		}
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	for _, n := range []int{1, 2, 10, 100} {
+		sample, outOfBag := Bootstrap(n, rand.New(rand.NewSource(1)))
+		if len(sample) != n {
+			t.Errorf("n = %v: sample has length %v", n, len(sample))
+			continue
+		}
+		drawn := make(map[int]bool)
+		for _, i := range sample {
+			if i < 0 || i >= n {
+				t.Errorf("n = %v: index %v out of range", n, i)
+			}
+			drawn[i] = true
+		}
+		if !sort.IntsAreSorted(outOfBag) {
+			t.Errorf("n = %v: out-of-bag indices %v not sorted", n, outOfBag)
+		}
+		for _, i := range outOfBag {
+			if drawn[i] {
+				t.Errorf("n = %v: out-of-bag index %v was drawn", n, i)
+			}
+		}
+		if len(drawn)+len(outOfBag) != n {
+			t.Errorf("n = %v: %v distinct drawn and %v out-of-bag indices", n, len(drawn), len(outOfBag))
+		}
+	}
+}
+
+func TestStratifiedKFold(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		counts map[int]int
+		k      int
+	}{
+		{name: "balanced", counts: map[int]int{0: 10, 1: 10}, k: 5},
+		{name: "imbalanced", counts: map[int]int{0: 30, 1: 3, 7: 7}, k: 3},
+		{name: "class smaller than k", counts: map[int]int{-1: 1, 2: 9}, k: 4},
+	} {
+		var labels []int
+		for c := -1; c <= 7; c++ {
+			for i := 0; i < test.counts[c]; i++ {
+				labels = append(labels, c)
+			}
+		}
+		rand.New(rand.NewSource(2)).Shuffle(len(labels), func(i, j int) { labels[i], labels[j] = labels[j], labels[i] })
+		folds := StratifiedKFold(labels, test.k, rand.New(rand.NewSource(1)))
+		if len(folds) != test.k {
+			t.Errorf("%v: %v folds, expected %v", test.name, len(folds), test.k)
+			continue
+		}
+		checkPartition(t, folds, len(labels), "StratifiedKFold "+test.name)
+		minSize, maxSize := len(labels), 0
+		for _, fold := range folds {
+			if len(fold) < minSize {
+				minSize = len(fold)
+			}
+			if len(fold) > maxSize {
+				maxSize = len(fold)
+			}
+		}
+		if maxSize-minSize > 1 {
+			t.Errorf("%v: fold sizes range from %v to %v", test.name, minSize, maxSize)
+		}
+		for c, count := range test.counts {
+			for f, fold := range folds {
+				var inFold int
+				for _, i := range fold {
+					if labels[i] == c {
+						inFold++
+					}
+				}
+				if inFold != count/test.k && inFold != count/test.k+1 {
+					t.Errorf("%v: fold %v has %v samples of class %v of %v", test.name, f, inFold, c, count)
+				}
+			}
+		}
+	}
+}
+
+func TestBatches(t *testing.T) {
+	for _, test := range []struct {
+		n, batchSize int
+		shuffle      bool
+		dropLast     bool
+		sizes        []int
+	}{
+		{n: 10, batchSize: 3, sizes: []int{3, 3, 3, 1}},
+		{n: 10, batchSize: 3, dropLast: true, sizes: []int{3, 3, 3}},
+		{n: 10, batchSize: 5, shuffle: true, sizes: []int{5, 5}},
+		{n: 2, batchSize: 5, sizes: []int{2}},
+		{n: 2, batchSize: 5, dropLast: true, sizes: nil},
+		{n: 0, batchSize: 1, sizes: nil},
+	} {
+		batches := Batches(test.n, test.batchSize, test.shuffle, test.dropLast, rand.New(rand.NewSource(1)))
+		var sizes []int
+		for _, batch := range batches {
+			sizes = append(sizes, len(batch))
+		}
+		if !reflect.DeepEqual(sizes, test.sizes) {
+			t.Errorf("n = %v, batchSize = %v, dropLast = %v: batch sizes %v, expected %v", test.n, test.batchSize, test.dropLast, sizes, test.sizes)
+			continue
+		}
+		if !test.dropLast {
+			checkPartition(t, batches, test.n, "Batches")
+		}
+		if !test.shuffle {
+			var all []int
+			for _, batch := range batches {
+				all = append(all, batch...)
+			}
+			if !sort.IntsAreSorted(all) {
+				t.Errorf("n = %v, batchSize = %v: unshuffled batches out of order: %v", test.n, test.batchSize, batches)
+			}
+		}
+		for i := 0; i+1 < len(batches); i++ {
+			if cap(batches[i]) != len(batches[i]) {
+				t.Errorf("n = %v, batchSize = %v: batch %v has spare capacity", test.n, test.batchSize, i)
+			}
+		}
+	}
+}
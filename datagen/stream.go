@@ -0,0 +1,56 @@
+package datagen
+
+import "math/rand"
+
+// Sampler draws a single sample using rnd
+type Sampler func(rnd *rand.Rand) (input, output []float64)
+
+// LinearSampler returns a Sampler for the model generated by Linear, with standard
+// normal inputs, the given coefficients and noise drawn from noise, which may be nil.
+func LinearSampler(coefficients []float64, noise Noiser) Sampler {
+	return func(rnd *rand.Rand) (input, output []float64) {
+		input = make([]float64, len(coefficients))
+		var y float64
+		for j, c := range coefficients {
+			input[j] = normFloat64(rnd)
+			y += c * input[j]
+		}
+		return input, []float64{y + draw(noise, rnd)}
+	}
+}
+
+// Stream draws n samples from sampler and calls f with each of them in turn, stopping
+// early if f returns false. Samples are generated only as they are consumed, so the
+// data set is never held in memory, which allows online learners to be tested on very
+// large data sets.
+func Stream(n int, sampler Sampler, rnd *rand.Rand, f func(input, output []float64) bool) {
+	for i := 0; i < n; i++ {
+		if !f(sampler(rnd)) {
+			return
+		}
+	}
+}
+
+// Sample is a single input/output pair
+type Sample struct {
+	Input, Output []float64
+}
+
+// StreamChan is like Stream, but sends the samples on the returned channel, which is
+// closed after n samples or once done is closed. rnd must not be used by the caller
+// until the channel is closed.
+func StreamChan(n int, sampler Sampler, rnd *rand.Rand, done <-chan struct{}) <-chan Sample {
+	c := make(chan Sample)
+	go func() {
+		defer close(c)
+		for i := 0; i < n; i++ {
+			in, out := sampler(rnd)
+			select {
+			case c <- Sample{Input: in, Output: out}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return c
+}
@@ -0,0 +1,62 @@
+package datagen
+
+import (
+	"math"
+	"math/rand"
+)
+
+// WeightKind is the distribution of a generated sample weight vector
+type WeightKind int
+
+const (
+	// UniformWeights are uniform in (0, 1]
+	UniformWeights WeightKind = iota
+	// ExponentialWeights are drawn from the exponential distribution with unit mean
+	ExponentialWeights
+	// LogNormalWeights are exp(z) for standard normal z, so some weights are many
+	// times larger than the rest
+	LogNormalWeights
+	// SpikeWeights are all one except for a random one percent (at least one), which
+	// are 1e6
+	SpikeWeights
+)
+
+// spikeWeight and spikeFraction define SpikeWeights
+const (
+	spikeWeight   = 1e6
+	spikeFraction = 0.01
+)
+
+// Weights returns n positive sample weights with the given distribution, for testing
+// weighted training beyond the trivial case where every weight is one.
+func Weights(n int, kind WeightKind, rnd *rand.Rand) []float64 {
+	weights := make([]float64, n)
+	switch kind {
+	default:
+		panic("datagen: unknown weight kind")
+	case UniformWeights:
+		for i := range weights {
+			weights[i] = 1 - uniform(rnd, 0, 1)
+		}
+	case ExponentialWeights:
+		for i := range weights {
+			weights[i] = -math.Log(1 - uniform(rnd, 0, 1))
+		}
+	case LogNormalWeights:
+		for i := range weights {
+			weights[i] = math.Exp(normFloat64(rnd))
+		}
+	case SpikeWeights:
+		for i := range weights {
+			weights[i] = 1
+		}
+		idx := choose(n, spikeFraction, rnd)
+		if len(idx) == 0 && n > 0 {
+			idx = []int{intn(rnd, n)}
+		}
+		for _, i := range idx {
+			weights[i] = spikeWeight
+		}
+	}
+	return weights
+}
@@ -0,0 +1,331 @@
+package regtest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/gonum/matrix/mat64"
+)
+
+const nDerivTests = 10
+
+// PredDeriver is a model that can compute the derivative of its prediction with
+// respect to the input. DerivPred stores the OutputDim×InputDim Jacobian in deriv,
+// allocating it if deriv is nil, and returns it.
+type PredDeriver interface {
+	Predictor
+	DerivPred(input []float64, deriv *mat64.Dense) (*mat64.Dense, error)
+}
+
+// TestPredDeriv compares the analytic Jacobian of the prediction with respect to the
+// input with central finite differences at random input points. If p is a
+// BatchPredictor, the perturbed inputs are predicted in PredictBatch calls of a
+// bounded number of points. The step size can be set with the FDStep option. On
+// failure the error at a sweep of step sizes is reported.
+func TestPredDeriv(t *testing.T, p PredDeriver, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	inputDim := p.InputDim()
+	outputDim := p.OutputDim()
+	predict := pointwise(func(x, y []float64) error {
+		_, err := p.Predict(x, y)
+		return err
+	})
+	if batch, ok := p.(BatchPredictor); ok {
+		predict = batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+			return batch.PredictBatch(points, nil)
+		})
+	}
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	input := make([]float64, inputDim)
+	for i := 0; i < nDerivTests; i++ {
+		for j := range input {
+			input[j] = rnd.NormFloat64()
+		}
+		deriv, err := p.DerivPred(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error computing DerivPred: %v", name, err)
+			return
+		}
+		r, c := deriv.Dims()
+		if r != outputDim || c != inputDim {
+			t.Errorf("%v: Wrong size returned by DerivPred. Expected %v×%v, found %v×%v", name, outputDim, inputDim, r, c)
+			return
+		}
+		fd, err := fdJacobianSteps(predict, input, outputDim, fdSteps(input, s.fdStep, false))
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		if !CheckMatricesEqual(t, fd, deriv, cmp, fmt.Sprintf("%v: deriv against finite difference at random input %v", name, i)) {
+			t.Logf("%v: error at a sweep of finite difference steps:%v", name, stepSweep(predict, input, false, deriv))
+			return
+		}
+	}
+}
+
+const nObjectivePerturbations = 5
+
+// defaultGradRegimes is the default of the GradRegimes option
+var defaultGradRegimes = []float64{1e-6, 1, 1e3}
+
+// ObjGrader is a training objective. ObjGrad returns the value of the objective at
+// parameters and stores the gradient with respect to the parameters in derivative.
+type ObjGrader interface {
+	ObjGrad(parameters, derivative []float64) float64
+}
+
+// PointsObjective is a training objective that can be evaluated at many parameter
+// vectors in one call. ObjPoints stores the objective at row i of parameters in
+// values[i].
+type PointsObjective interface {
+	ObjPoints(parameters *mat64.Dense, values []float64)
+}
+
+// CheckObjectiveGrad checks the gradient of a training objective against central
+// finite differences, at params, at random perturbations of params, and at random
+// parameter vectors of each scale set by the GradRegimes option, by default 1e-6, 1
+// and 1e3, since many gradient bugs only appear near zero or at large magnitude.
+// params is not modified. The step size can be set with the FDStep option; at the
+// random parameter vectors of each scale it is scaled by the magnitude of each
+// parameter, and at params and its perturbations it is used as is. If obj is also a
+// PointsObjective, the perturbed parameters at each point are evaluated in ObjPoints
+// calls of a bounded number of points. On failure the error at a sweep of step sizes
+// is reported.
+func CheckObjectiveGrad(t *testing.T, obj ObjGrader, params []float64, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := len(params)
+	x := make([]float64, n)
+	copy(x, params)
+	xCpy := make([]float64, n)
+	derivative := make([]float64, n)
+	fdDerivative := make([]float64, n)
+	scratch := make([]float64, n)
+	objective := scalar(func(x []float64) float64 {
+		return obj.ObjGrad(x, scratch)
+	})
+	if po, ok := obj.(PointsObjective); ok {
+		objective = batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+			r, _ := points.Dims()
+			values := make([]float64, r)
+			po.ObjPoints(points, values)
+			return mat64.NewDense(r, 1, values), nil
+		})
+	}
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	for i := 0; i <= nObjectivePerturbations+len(s.gradRegimes); i++ {
+		switch {
+		case i == 0:
+		case i <= nObjectivePerturbations:
+			for j := range x {
+				x[j] = params[j] + rnd.NormFloat64()
+			}
+		default:
+			scale := s.gradRegimes[i-nObjectivePerturbations-1]
+			for j := range x {
+				x[j] = scale * rnd.NormFloat64()
+			}
+		}
+		copy(xCpy, x)
+		obj.ObjGrad(x, derivative)
+		if !floats.Equal(x, xCpy) {
+			t.Errorf("%v: parameters modified during call to ObjGrad", name)
+			return
+		}
+		// The values of an objective always have the right size. Only the random
+		// parameter vectors of each scale use relative steps.
+		relative := i > nObjectivePerturbations
+		fd, _ := fdJacobianSteps(objective, x, 1, fdSteps(x, s.fdStep, relative))
+		fd.Row(fdDerivative, 0)
+		if d := cmp.diff(fdDerivative, derivative); d != "" {
+			t.Errorf("%v: gradient doesn't match finite difference %v: %v%v", name, objectivePoint(i, s.gradRegimes), d, gradientSweep(objective, x, relative, derivative))
+			return
+		}
+	}
+}
+
+// objectivePoint describes the i-th point at which CheckObjectiveGrad checks the
+// gradient, for failure messages
+func objectivePoint(i int, regimes []float64) string {
+	switch {
+	case i == 0:
+		return "at params"
+	case i <= nObjectivePerturbations:
+		return fmt.Sprintf("at random perturbation %v of params", i)
+	default:
+		return fmt.Sprintf("at random parameters of scale %v", regimes[i-nObjectivePerturbations-1])
+	}
+}
+
+// HessianObjective is a training objective that can compute its Hessian with respect
+// to the parameters. Hessian stores the n×n Hessian at parameters in hessian.
+type HessianObjective interface {
+	ObjGrader
+	Hessian(parameters []float64, hessian *mat64.Dense)
+}
+
+// PointsGrader is a training objective that can compute its gradient at many
+// parameter vectors in one call. GradPoints stores the gradient at row i of parameters
+// in row i of gradients.
+type PointsGrader interface {
+	GradPoints(parameters, gradients *mat64.Dense)
+}
+
+// CheckHessian checks that the Hessian of obj at params is symmetric, to within the
+// Tol option if set and tol otherwise, and matches central finite differences of the
+// gradient. If obj is also a PointsGrader, the gradients at the perturbed parameters
+// are computed in GradPoints calls of a bounded number of points. params is not
+// modified. The step size can be set with the FDStep option. On failure the error at
+// a sweep of step sizes is reported.
+func CheckHessian(t *testing.T, obj HessianObjective, params []float64, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	n := len(params)
+	x := make([]float64, n)
+	copy(x, params)
+
+	hessian := mat64.NewDense(n, n, nil)
+	obj.Hessian(x, hessian)
+	if !floats.Equal(x, params) {
+		t.Errorf("%v: parameters modified during call to Hessian", name)
+		return
+	}
+	hessianT := mat64.NewDense(n, n, nil)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			hessianT.Set(j, i, hessian.At(i, j))
+		}
+	}
+	if !CheckMatricesEqual(t, hessianT, hessian, s.deterministicTol(tol), name+": Hessian against its transpose") {
+		return
+	}
+
+	grad := pointwise(func(x, g []float64) error {
+		obj.ObjGrad(x, g)
+		return nil
+	})
+	if pg, ok := obj.(PointsGrader); ok {
+		grad = batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+			r, _ := points.Dims()
+			gradients := mat64.NewDense(r, n, nil)
+			pg.GradPoints(points, gradients)
+			return gradients, nil
+		})
+	}
+	// The gradients always have the right size
+	fd, _ := fdJacobianSteps(grad, x, n, fdSteps(x, s.fdStep, false))
+	if !CheckMatricesEqual(t, fd, hessian, Tolerance{Abs: tol, Rel: tol}, name+": Hessian against finite difference") {
+		t.Logf("%v: error at a sweep of finite difference steps:%v", name, stepSweep(grad, x, false, hessian))
+	}
+}
+
+// ParamDeriver is a model that can compute the derivative of its prediction with
+// respect to its parameters. DerivParams stores the OutputDim×NumParameters Jacobian
+// in deriv, allocating it if deriv is nil, and returns it.
+type ParamDeriver interface {
+	ParameterPredictor
+	DerivParams(input []float64, deriv *mat64.Dense) (*mat64.Dense, error)
+}
+
+// CheckJacobian compares the analytic Jacobians of a (possibly vector-valued) model with
+// column-wise central finite differences at random inputs. The Jacobian with respect to
+// the input is checked if p is a PredDeriver, and the Jacobian with respect to the
+// parameters is checked if p is a ParamDeriver. The input Jacobian is checked by
+// TestPredDeriv, in batches for a BatchPredictor; the parameter Jacobian is evaluated
+// one point at a time, since each perturbed point needs its own parameters. The
+// parameters of p are restored before returning. The step size can be set with the
+// FDStep option. On failure the error at a sweep of step sizes is reported.
+func CheckJacobian(t *testing.T, p Predictor, tol float64, name string, opts ...Option) {
+	pd, isPred := p.(PredDeriver)
+	pp, isParam := p.(ParamDeriver)
+	if !isPred && !isParam {
+		t.Errorf("%v: CheckJacobian called on a model without analytic Jacobians", name)
+		return
+	}
+	if isPred {
+		TestPredDeriv(t, pd, tol, name, opts...)
+	}
+	if !isParam {
+		return
+	}
+
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	outputDim := pp.OutputDim()
+	numParameters := pp.NumParameters()
+	orig := pp.Parameters(nil)
+	defer pp.SetParameters(orig)
+
+	var input []float64
+	predict := pointwise(func(x, y []float64) error {
+		pp.SetParameters(x)
+		_, err := pp.Predict(input, y)
+		return err
+	})
+	params := make([]float64, numParameters)
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	for i, in := range randomProbes(nDerivTests, pp.InputDim(), rnd) {
+		input = in
+		copy(params, orig)
+		pp.SetParameters(params)
+		deriv, err := pp.DerivParams(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error computing DerivParams: %v", name, err)
+			return
+		}
+		r, c := deriv.Dims()
+		if r != outputDim || c != numParameters {
+			t.Errorf("%v: Wrong size returned by DerivParams. Expected %v×%v, found %v×%v", name, outputDim, numParameters, r, c)
+			return
+		}
+		fd, err := fdJacobianSteps(predict, params, outputDim, fdSteps(params, s.fdStep, false))
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		if !CheckMatricesEqual(t, fd, deriv, cmp, fmt.Sprintf("%v: parameter Jacobian against finite difference at random input %v", name, i)) {
+			t.Logf("%v: error at a sweep of finite difference steps:%v", name, stepSweep(predict, params, false, deriv))
+			return
+		}
+	}
+}
+
+// TransformDeriver is a Transformer, such as a featurizer or scaler, that can compute
+// the derivative of its output with respect to its input, for backpropagating through
+// preprocessing. DerivTransform stores the OutputDim×InputDim Jacobian in deriv,
+// allocating it if deriv is nil, and returns it.
+type TransformDeriver interface {
+	Transformer
+	DerivTransform(input []float64, deriv *mat64.Dense) (*mat64.Dense, error)
+}
+
+// CheckTransformJacobian compares the Jacobian from DerivTransform with column-wise
+// central finite differences of Transform at random inputs. The step size can be set
+// with the FDStep option. On failure the error at a sweep of step sizes is reported.
+func CheckTransformJacobian(t *testing.T, tr TransformDeriver, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	outputDim := tr.OutputDim()
+	transform := pointwise(func(x, y []float64) error {
+		_, err := tr.Transform(x, y)
+		return err
+	})
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	for i, input := range randomProbes(nDerivTests, tr.InputDim(), rnd) {
+		deriv, err := tr.DerivTransform(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error computing DerivTransform: %v", name, err)
+			return
+		}
+		fd, err := fdJacobianSteps(transform, input, outputDim, fdSteps(input, s.fdStep, false))
+		if err != nil {
+			t.Errorf("%v: Error transforming: %v", name, err)
+			return
+		}
+		if !CheckMatricesEqual(t, fd, deriv, cmp, fmt.Sprintf("%v: transform Jacobian against finite difference at random input %v", name, i)) {
+			t.Logf("%v: error at a sweep of finite difference steps:%v", name, stepSweep(transform, input, false, deriv))
+			return
+		}
+	}
+}
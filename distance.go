@@ -0,0 +1,65 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+)
+
+const (
+	nDistanceTests = 100
+	distanceTol    = 1e-12
+)
+
+// Distancer is a distance function between two points of the same dimension
+type Distancer interface {
+	Distance(x, y []float64) float64
+}
+
+// TestDistancer tests the metric axioms on random points of dimension dim:
+// non-negativity, d(x,x) == 0 and d(x,y) > 0 for x != y, and symmetry. The triangle
+// inequality is checked on random triples only when the Triangle option is given,
+// since many useful dissimilarities (e.g. squared Euclidean) violate it. Symmetry
+// and the triangle inequality are checked to within the Tol option, by default an
+// absolute and relative tolerance of 1e-12.
+func TestDistancer(t *testing.T, d Distancer, dim int, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	cmp := s.deterministicTol(distanceTol)
+	x := make([]float64, dim)
+	y := make([]float64, dim)
+	z := make([]float64, dim)
+	for i := 0; i < nDistanceTests; i++ {
+		for j := 0; j < dim; j++ {
+			x[j] = rnd.NormFloat64()
+			y[j] = rnd.NormFloat64()
+			z[j] = rnd.NormFloat64()
+		}
+		dxx := d.Distance(x, x)
+		if dxx != 0 {
+			t.Errorf("%v: distance from a point to itself is not zero. Found %v", name, dxx)
+			return
+		}
+		dxy := d.Distance(x, y)
+		if math.IsNaN(dxy) || dxy < 0 {
+			t.Errorf("%v: distance is negative or NaN. Found %v", name, dxy)
+			return
+		}
+		if dxy == 0 {
+			t.Errorf("%v: distance between distinct points is zero", name)
+			return
+		}
+		dyx := d.Distance(y, x)
+		if !cmp.equal(dxy, dyx) {
+			t.Errorf("%v: distance is not symmetric. d(x,y) = %v, d(y,x) = %v", name, dxy, dyx)
+			return
+		}
+		if s.triangle {
+			dxz := d.Distance(x, z)
+			dzy := d.Distance(z, y)
+			if dxy > dxz+dzy && !cmp.equal(dxy, dxz+dzy) {
+				t.Errorf("%v: triangle inequality violated. d(x,y) = %v, d(x,z) + d(z,y) = %v", name, dxy, dxz+dzy)
+				return
+			}
+		}
+	}
+}
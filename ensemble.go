@@ -0,0 +1,103 @@
+package regtest
+
+import (
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+// Ensemble is a predictor whose prediction is the weighted mean of the predictions
+// of its members
+type Ensemble interface {
+	Predictor
+	NumMembers() int
+	Member(i int) Predictor
+	MemberWeight(i int) float64
+}
+
+// MemberRemover is an Ensemble that can remove one of its members
+type MemberRemover interface {
+	RemoveMember(i int)
+}
+
+// TestEnsemble tests an averaging ensemble with nMembers members on random inputs.
+// It checks the member count, and that the prediction equals the weighted mean of
+// the member predictions to within tol. If the ensemble is a MemberRemover and has a
+// zero-weighted member, that member is removed and the predictions must not change.
+// A tolerance set with the Tol option overrides tol.
+func TestEnsemble(t *testing.T, e Ensemble, nMembers int, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	cmp := s.deterministicTol(tol)
+	if e.NumMembers() != nMembers {
+		t.Errorf("%v: Wrong number of members. Expected %v, found %v", name, nMembers, e.NumMembers())
+		return
+	}
+	probes := randomProbes(nProbes, e.InputDim(), rnd)
+	outputDim := e.OutputDim()
+
+	zeroMember := -1
+	var totalWeight float64
+	for i := 0; i < nMembers; i++ {
+		w := e.MemberWeight(i)
+		if w < 0 {
+			t.Errorf("%v: member %v has negative weight %v", name, i, w)
+			return
+		}
+		if w == 0 && zeroMember < 0 {
+			zeroMember = i
+		}
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		t.Errorf("%v: all members have zero weight", name)
+		return
+	}
+
+	want := make([]float64, outputDim)
+	for _, input := range probes {
+		for j := range want {
+			want[j] = 0
+		}
+		for i := 0; i < nMembers; i++ {
+			pred, err := e.Member(i).Predict(input, nil)
+			if err != nil {
+				t.Errorf("%v: Error predicting with member %v: %v", name, i, err)
+				return
+			}
+			floats.AddScaled(want, e.MemberWeight(i)/totalWeight, pred)
+		}
+		got, err := e.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting with ensemble: %v", name, err)
+			return
+		}
+		if d := cmp.diff(want, got); d != "" {
+			t.Errorf("%v: ensemble prediction is not the weighted mean of its members at input %v: %v", name, input, d)
+			return
+		}
+	}
+
+	r, ok := e.(MemberRemover)
+	if !ok || zeroMember < 0 {
+		return
+	}
+	before, err := predictAll(e, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with ensemble: %v", name, err)
+		return
+	}
+	r.RemoveMember(zeroMember)
+	if e.NumMembers() != nMembers-1 {
+		t.Errorf("%v: Wrong number of members after removal. Expected %v, found %v", name, nMembers-1, e.NumMembers())
+		return
+	}
+	after, err := predictAll(e, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting with ensemble after removal: %v", name, err)
+		return
+	}
+	if d := cmp.diffRows(before, after); d != "" {
+		t.Errorf("%v: removing zero-weighted member %v changed the predictions: %v", name, zeroMember, d)
+	}
+}
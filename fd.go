@@ -0,0 +1,188 @@
+package regtest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// fdFunc is a vector-valued function whose finite differences are computed by
+// fdJacobianSteps. point stores the outputs at x in y. If batch is not nil, it
+// returns the outputs at row i of points in row i of the result, and is used instead
+// of point so that models with batch evaluation, such as a BatchPredictor or a
+// PointsObjective, pay their per-call overhead once per chunk of perturbed points
+// rather than once per point.
+type fdFunc struct {
+	point func(x, y []float64) error
+	batch func(points *mat64.Dense) (mat64.Matrix, error)
+}
+
+// pointwise returns an fdFunc that evaluates f, which stores its outputs at x in y,
+// at one point at a time
+func pointwise(f func(x, y []float64) error) fdFunc {
+	return fdFunc{point: f}
+}
+
+// scalar returns an fdFunc that evaluates the scalar function f at one point at a
+// time
+func scalar(f func(x []float64) float64) fdFunc {
+	return pointwise(func(x, y []float64) error {
+		y[0] = f(x)
+		return nil
+	})
+}
+
+// batched returns an fdFunc that evaluates f at many points in one call
+func batched(f func(points *mat64.Dense) (mat64.Matrix, error)) fdFunc {
+	return fdFunc{batch: f}
+}
+
+// fdChunk is the number of coordinates whose perturbed points are evaluated in a
+// single batch call, which bounds the memory of a batched finite difference to
+// 2·fdChunk points rather than 2·len(x)
+const fdChunk = 64
+
+// fdSteps returns the step for each coordinate of x: h, or h*max(1, |x[i]|) if
+// relative, so that the step is relative to the magnitude of large coordinates
+func fdSteps(x []float64, h float64, relative bool) []float64 {
+	steps := make([]float64, len(x))
+	for i := range steps {
+		steps[i] = h
+		if relative {
+			steps[i] *= math.Max(1, math.Abs(x[i]))
+		}
+	}
+	return steps
+}
+
+// fdPoints returns the central difference points of x for coordinates start to end
+// as the rows of a matrix, where rows 2k and 2k+1 are x with steps[start+k] added to
+// and subtracted from x[start+k]
+func fdPoints(x, steps []float64, start, end int) *mat64.Dense {
+	points := mat64.NewDense(2*(end-start), len(x), nil)
+	for k, j := 0, start; j < end; k, j = k+1, j+1 {
+		points.SetRow(2*k, x)
+		points.SetRow(2*k+1, x)
+		points.Set(2*k, j, x[j]+steps[j])
+		points.Set(2*k+1, j, x[j]-steps[j])
+	}
+	return points
+}
+
+// fdJacobianSteps returns the central finite difference approximation to the
+// Jacobian of f at x, where f has m outputs, using the step steps[j] for coordinate j.
+// Element (i, j) of the result is the derivative of output i with respect to x[j]. A
+// pointwise f is evaluated with each coordinate of x perturbed in place, so x is
+// modified during the call but is restored before returning; a batched f is
+// evaluated at the perturbed points of fdChunk coordinates at a time. An error is
+// returned if f fails or returns a result of the wrong size.
+func fdJacobianSteps(f fdFunc, x []float64, m int, steps []float64) (*mat64.Dense, error) {
+	n := len(x)
+	if len(steps) != n {
+		panic("regtest: step length mismatch")
+	}
+	jac := mat64.NewDense(m, n, nil)
+	if f.batch == nil {
+		y1 := make([]float64, m)
+		y2 := make([]float64, m)
+		for j := range x {
+			orig := x[j]
+			x[j] = orig + steps[j]
+			err := f.point(x, y1)
+			if err == nil {
+				x[j] = orig - steps[j]
+				err = f.point(x, y2)
+			}
+			x[j] = orig
+			if err != nil {
+				return nil, err
+			}
+			for i := 0; i < m; i++ {
+				jac.Set(i, j, (y1[i]-y2[i])/(2*steps[j]))
+			}
+		}
+		return jac, nil
+	}
+	for start := 0; start < n; start += fdChunk {
+		end := start + fdChunk
+		if end > n {
+			end = n
+		}
+		y, err := f.batch(fdPoints(x, steps, start, end))
+		if err != nil {
+			return nil, err
+		}
+		nPoints := 2 * (end - start)
+		if r, c := y.Dims(); r != nPoints || c != m {
+			return nil, fmt.Errorf("batch evaluation of %v points returned %v×%v outputs, expected %v×%v", nPoints, r, c, nPoints, m)
+		}
+		for k, j := 0, start; j < end; k, j = k+1, j+1 {
+			for i := 0; i < m; i++ {
+				jac.Set(i, j, (y.At(2*k, i)-y.At(2*k+1, i))/(2*steps[j]))
+			}
+		}
+	}
+	return jac, nil
+}
+
+// fdGradient stores the central finite difference approximation to the gradient
+// of f at x into grad, using step size h. x is modified during the call but is
+// restored before returning.
+func fdGradient(f func(x []float64) float64, x, grad []float64, h float64) {
+	fdGradientScaled(f, x, grad, h, false)
+}
+
+// fdGradientRelative is like fdGradient, but uses the step h*max(1, |x[i]|) for
+// coordinate i, so that the step is relative to the magnitude of large coordinates
+func fdGradientRelative(f func(x []float64) float64, x, grad []float64, h float64) {
+	fdGradientScaled(f, x, grad, h, true)
+}
+
+// fdGradientScaled is fdGradient with relative steps if relative is true
+func fdGradientScaled(f func(x []float64) float64, x, grad []float64, h float64, relative bool) {
+	if len(x) != len(grad) {
+		panic("regtest: gradient length mismatch")
+	}
+	// A scalar function can't return the wrong size
+	jac, _ := fdJacobianSteps(scalar(f), x, 1, fdSteps(x, h, relative))
+	jac.Row(grad, 0)
+}
+
+// sweepSteps are the finite difference step sizes tried by stepSweep
+var sweepSteps = []float64{1e-2, 1e-3, 1e-4, 1e-5, 1e-6, 1e-7, 1e-8, 1e-9, 1e-10}
+
+// stepSweep returns a table of the largest absolute difference between analytic and
+// the central finite difference Jacobian of f at x for each step in sweepSteps, for
+// failure messages. If relative, each step is scaled by max(1, |x[i]|) as in the
+// check being reported. An error that falls with the square of the step until
+// rounding takes over points to truncation error in the check; an error that stays
+// large at every step points to a wrong derivative. x is restored before returning.
+func stepSweep(f fdFunc, x []float64, relative bool, analytic mat64.Matrix) string {
+	m, _ := analytic.Dims()
+	table := "\n\tstep\tmax abs error"
+	for _, h := range sweepSteps {
+		fd, err := fdJacobianSteps(f, x, m, fdSteps(x, h, relative))
+		if err != nil {
+			table += fmt.Sprintf("\n\t%.0e\t%v", h, err)
+			continue
+		}
+		var worst float64
+		for i := 0; i < m; i++ {
+			for j := range x {
+				e := math.Abs(fd.At(i, j) - analytic.At(i, j))
+				if math.IsNaN(e) {
+					e = math.Inf(1)
+				}
+				worst = math.Max(worst, e)
+			}
+		}
+		table += fmt.Sprintf("\n\t%.0e\t%.3g", h, worst)
+	}
+	return table
+}
+
+// gradientSweep is stepSweep for the gradient of a scalar function
+func gradientSweep(f fdFunc, x []float64, relative bool, analytic []float64) string {
+	return stepSweep(f, x, relative, mat64.NewDense(1, len(analytic), analytic))
+}
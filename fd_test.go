@@ -0,0 +1,218 @@
+package regtest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+func TestFDSteps(t *testing.T) {
+	x := []float64{0, 0.5, -3, 1e4}
+	for _, test := range []struct {
+		relative bool
+		steps    []float64
+	}{
+		{relative: false, steps: []float64{1e-3, 1e-3, 1e-3, 1e-3}},
+		{relative: true, steps: []float64{1e-3, 1e-3, 3e-3, 10}},
+	} {
+		steps := fdSteps(x, 1e-3, test.relative)
+		if d := (Tolerance{Rel: 1e-14}).diff(test.steps, steps); d != "" {
+			t.Errorf("relative %v: steps mismatch: %v", test.relative, d)
+		}
+	}
+}
+
+// sweepRows returns the rows of a stepSweep table after its header
+func sweepRows(t *testing.T, table string) []string {
+	lines := strings.Split(table, "\n\t")
+	if lines[0] != "" || lines[1] != "step\tmax abs error" {
+		t.Fatalf("sweep table has header %q", table)
+	}
+	return lines[2:]
+}
+
+func TestStepSweep(t *testing.T) {
+	// f(x) = x³ has central difference error h² at any x, so the error falls with
+	// the square of the step until rounding takes over
+	cube := scalar(func(x []float64) float64 { return x[0] * x[0] * x[0] })
+	x := []float64{1}
+	rows := sweepRows(t, gradientSweep(cube, x, false, []float64{3}))
+	if len(rows) != len(sweepSteps) {
+		t.Fatalf("sweep has %v rows, expected %v", len(rows), len(sweepSteps))
+	}
+	if rows[0] != "1e-02\t0.0001" {
+		t.Errorf("first sweep row is %q, expected %q", rows[0], "1e-02\t0.0001")
+	}
+
+	// A wrong derivative is wrong by the same amount at every step
+	for i, row := range sweepRows(t, gradientSweep(cube, x, false, []float64{4})) {
+		if !strings.HasSuffix(row, "\t1") {
+			t.Errorf("sweep row %v with a wrong derivative is %q", i, row)
+		}
+	}
+
+	// Relative steps are scaled by |x|, so the truncation error at x = 100 with
+	// relative steps is 100² times that with absolute steps
+	x = []float64{100}
+	abs := sweepRows(t, gradientSweep(cube, x, false, []float64{3e4}))
+	rel := sweepRows(t, gradientSweep(cube, x, true, []float64{3e4}))
+	if abs[0] != "1e-02\t0.0001" || rel[0] != "1e-02\t1" {
+		t.Errorf("first sweep rows are %q and %q with absolute and relative steps", abs[0], rel[0])
+	}
+}
+
+func TestStepSweepError(t *testing.T) {
+	f := batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+		return nil, errors.New("failed")
+	})
+	for i, row := range sweepRows(t, gradientSweep(f, []float64{1, 2}, false, []float64{0, 0})) {
+		if !strings.HasSuffix(row, "\tfailed") {
+			t.Errorf("sweep row %v doesn't report the error: %q", i, row)
+		}
+	}
+}
+
+// quadJacobian is the Jacobian of y = (x0·x1, x0² + 2x1) at x
+func quadJacobian(x []float64) *mat64.Dense {
+	return mat64.NewDense(2, 2, []float64{
+		x[1], x[0],
+		2 * x[0], 2,
+	})
+}
+
+func quad(x, y []float64) error {
+	y[0] = x[0] * x[1]
+	y[1] = x[0]*x[0] + 2*x[1]
+	return nil
+}
+
+// quadBatch evaluates quad at every row of points, counting its calls and the
+// largest number of points in a call
+func quadBatch(calls, maxPoints *int) fdFunc {
+	return batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+		*calls++
+		r, _ := points.Dims()
+		if r > *maxPoints {
+			*maxPoints = r
+		}
+		out := mat64.NewDense(r, 2, nil)
+		y := make([]float64, 2)
+		for i := 0; i < r; i++ {
+			quad(points.Row(nil, i), y)
+			out.SetRow(i, y)
+		}
+		return out, nil
+	})
+}
+
+func TestFDJacobianSteps(t *testing.T) {
+	x := []float64{1.5, -2}
+	steps := fdSteps(x, 1e-6, false)
+	want := quadJacobian(x)
+
+	jac, err := fdJacobianSteps(pointwise(quad), x, 2, steps)
+	if err != nil {
+		t.Fatalf("pointwise: unexpected error: %v", err)
+	}
+	CheckMatricesEqual(t, want, jac, Tolerance{Abs: 1e-8}, "pointwise")
+
+	var calls, maxPoints int
+	jac, err = fdJacobianSteps(quadBatch(&calls, &maxPoints), x, 2, steps)
+	if err != nil {
+		t.Fatalf("batch: unexpected error: %v", err)
+	}
+	CheckMatricesEqual(t, want, jac, Tolerance{Abs: 1e-8}, "batch")
+	if calls != 1 || maxPoints != 4 {
+		t.Errorf("batch evaluated %v times at up to %v points, expected once at 4", calls, maxPoints)
+	}
+
+	// x must be restored after being perturbed in place
+	if x[0] != 1.5 || x[1] != -2 {
+		t.Errorf("x modified to %v", x)
+	}
+}
+
+func TestFDJacobianStepsChunks(t *testing.T) {
+	// Only the first two coordinates affect quad, so the rest have zero derivative
+	n := 2*fdChunk + 3
+	x := make([]float64, n)
+	x[0], x[1] = 1.5, -2
+	want := mat64.NewDense(2, n, nil)
+	q := quadJacobian(x)
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			want.Set(i, j, q.At(i, j))
+		}
+	}
+	var calls, maxPoints int
+	jac, err := fdJacobianSteps(quadBatch(&calls, &maxPoints), x, 2, fdSteps(x, 1e-6, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	CheckMatricesEqual(t, want, jac, Tolerance{Abs: 1e-8}, "chunked batch")
+	if calls != 3 {
+		t.Errorf("batch evaluated %v times, expected 3", calls)
+	}
+	if maxPoints != 2*fdChunk {
+		t.Errorf("batch evaluated at up to %v points, expected %v", maxPoints, 2*fdChunk)
+	}
+}
+
+func TestFDJacobianStepsErrors(t *testing.T) {
+	x := []float64{1, 2}
+	steps := fdSteps(x, 1e-6, false)
+	for _, test := range []struct {
+		name string
+		f    fdFunc
+		err  string
+	}{
+		{
+			name: "wrong rows",
+			f: batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+				return mat64.NewDense(3, 2, nil), nil
+			}),
+			err: "batch evaluation of 4 points returned 3×2 outputs, expected 4×2",
+		},
+		{
+			name: "wrong columns",
+			f: batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+				return mat64.NewDense(4, 1, nil), nil
+			}),
+			err: "batch evaluation of 4 points returned 4×1 outputs, expected 4×2",
+		},
+		{
+			name: "pointwise",
+			f: pointwise(func(x, y []float64) error {
+				return errors.New("failed")
+			}),
+			err: "failed",
+		},
+	} {
+		_, err := fdJacobianSteps(test.f, x, 2, steps)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%v: error is %v, expected %q", test.name, err, test.err)
+		}
+		if x[0] != 1 || x[1] != 2 {
+			t.Errorf("%v: x modified to %v", test.name, x)
+		}
+	}
+}
+
+func TestFDGradient(t *testing.T) {
+	// f(x) = x0²·x1 + x1³
+	f := func(x []float64) float64 { return x[0]*x[0]*x[1] + x[1]*x[1]*x[1] }
+	for _, x := range [][]float64{{1, 2}, {-3, 0.5}, {1e3, -1e3}} {
+		want := []float64{2 * x[0] * x[1], x[0]*x[0] + 3*x[1]*x[1]}
+		grad := make([]float64, 2)
+		fdGradientRelative(f, x, grad, 1e-6)
+		if d := (Tolerance{Rel: 1e-6}).diff(want, grad); d != "" {
+			t.Errorf("relative gradient at %v: %v", x, d)
+		}
+		fdGradient(f, x, grad, 1e-6)
+		if d := (Tolerance{Rel: 1e-6}).diff(want, grad); d != "" {
+			t.Errorf("gradient at %v: %v", x, d)
+		}
+	}
+}
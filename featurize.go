@@ -0,0 +1,75 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+const nFeaturizeTests = 20
+
+// Featurizer maps an input into a feature vector of length NumFeatures.
+// Featurize stores the features in feature, which must have length NumFeatures.
+type Featurizer interface {
+	Featurize(input, feature []float64)
+	NumFeatures() int
+}
+
+// TestFeaturize tests a featurizer on random inputs of length inputDim. It checks
+// that every feature is written, that repeated calls give identical features, that
+// the input is not modified, and that a nil feature slice or an input or feature of
+// the wrong length panics.
+func TestFeaturize(t *testing.T, f Featurizer, inputDim int, name string, opts ...Option) {
+	rnd := newSettings(opts).rand(t)
+	nFeatures := f.NumFeatures()
+	input := make([]float64, inputDim)
+	inputCpy := make([]float64, inputDim)
+	feature := make([]float64, nFeatures)
+	feature2 := make([]float64, nFeatures)
+	for i := 0; i < nFeaturizeTests; i++ {
+		for j := range input {
+			input[j] = rnd.NormFloat64()
+		}
+		copy(inputCpy, input)
+		for j := range feature {
+			feature[j] = math.NaN()
+			feature2[j] = rnd.NormFloat64()
+		}
+		f.Featurize(input, feature)
+		if !floats.Equal(input, inputCpy) {
+			t.Errorf("%v: input modified during call to Featurize", name)
+			return
+		}
+		if floats.HasNaN(feature) {
+			t.Errorf("%v: Featurize did not fill every feature", name)
+			return
+		}
+		f.Featurize(input, feature2)
+		if !floats.Equal(feature, feature2) {
+			t.Errorf("%v: Featurize is not deterministic. First %v, second %v", name, feature, feature2)
+			return
+		}
+	}
+
+	g := func(input, feature []float64) func() {
+		return func() {
+			f.Featurize(input, feature)
+		}
+	}
+	if nFeatures > 0 && !panics(g(input, nil)) {
+		t.Errorf("%v: Featurize did not panic with a nil feature", name)
+	}
+	if !panics(g(input, make([]float64, nFeatures+1))) {
+		t.Errorf("%v: Featurize did not panic with a feature too long", name)
+	}
+	if nFeatures > 1 && !panics(g(input, make([]float64, nFeatures-1))) {
+		t.Errorf("%v: Featurize did not panic with a feature too short", name)
+	}
+	if !panics(g(make([]float64, inputDim+1), feature)) {
+		t.Errorf("%v: Featurize did not panic with an input too long", name)
+	}
+	if inputDim > 1 && !panics(g(make([]float64, inputDim-1), feature)) {
+		t.Errorf("%v: Featurize did not panic with an input too short", name)
+	}
+}
@@ -0,0 +1,96 @@
+package regtest
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// FuzzSetParameters is a fuzz target for SetParameters. Each fuzz input is decoded as
+// a slice of float64 (8 little-endian bytes each), and given to SetParameters on a
+// fresh model from newModel. If the slice has the wrong length, SetParameters must
+// panic. If it has the right length, SetParameters must succeed and Parameters must
+// return the same values, except that a model may panic on NaN or ±Inf.
+//
+// Use it from a fuzz test:
+//
+//	func FuzzMyModel(f *testing.F) {
+//		regtest.FuzzSetParameters(f, func() regtest.ParameterGetterSetter { return NewMyModel() })
+//	}
+func FuzzSetParameters(f *testing.F, newModel func() ParameterGetterSetter) {
+	n := newModel().NumParameters()
+	seeds := [][]float64{
+		make([]float64, n),
+		make([]float64, n+1),
+		{math.NaN()},
+		{math.Inf(1), math.Inf(-1)},
+	}
+	if n > 0 {
+		seeds = append(seeds, make([]float64, n-1))
+		nan := make([]float64, n)
+		nan[0] = math.NaN()
+		seeds = append(seeds, nan)
+		inf := make([]float64, n)
+		inf[n-1] = math.Inf(-1)
+		seeds = append(seeds, inf)
+	}
+	for _, seed := range seeds {
+		f.Add(encodeFloats(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		params := decodeFloats(data)
+		p := newModel()
+		if len(params) != p.NumParameters() {
+			if !panics(func() { p.SetParameters(params) }) {
+				t.Errorf("SetParameters did not panic with %v parameters, NumParameters is %v", len(params), p.NumParameters())
+			}
+			return
+		}
+		finite := true
+		for _, v := range params {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				finite = false
+			}
+		}
+		var b bool
+		if finite {
+			b = maybe(func() { p.SetParameters(params) })
+		} else {
+			b = panics(func() { p.SetParameters(params) })
+		}
+		if b {
+			return
+		}
+		got := p.Parameters(nil)
+		if len(got) != len(params) {
+			t.Errorf("Parameters returned %v parameters after setting %v", len(got), len(params))
+			return
+		}
+		exact := Tolerance{NaNEqual: true}
+		for i := range got {
+			if !exact.equal(got[i], params[i]) {
+				t.Errorf("parameter %v did not round trip. Set %v, got %v", i, params[i], got[i])
+				return
+			}
+		}
+	})
+}
+
+// encodeFloats encodes x as 8 little-endian bytes per element
+func encodeFloats(x []float64) []byte {
+	b := make([]byte, 8*len(x))
+	for i, v := range x {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return b
+}
+
+// decodeFloats is the inverse of encodeFloats. Trailing bytes are ignored.
+func decodeFloats(b []byte) []float64 {
+	x := make([]float64, len(b)/8)
+	for i := range x {
+		x[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return x
+}
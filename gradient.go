@@ -0,0 +1,271 @@
+package regtest
+
+import (
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/gonum/matrix/mat64"
+)
+
+// CheckGradient checks an analytic gradient against central finite differences at x.
+// f returns the function value and grad stores the gradient at x in g. The finite
+// difference step for coordinate i is h*max(1, |x[i]|), where h is set by the FDStep
+// option, so that coordinates with large magnitude are not swamped by rounding. A
+// coordinate matches if the analytic and finite difference values are equal to within
+// absTol or relTol, and on failure the worst offending coordinates are reported along
+// with the error at a sweep of step sizes. x is not modified.
+func CheckGradient(t *testing.T, f func(x []float64) float64, grad func(x, g []float64), x []float64, absTol, relTol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	n := len(x)
+	xCpy := make([]float64, n)
+	copy(xCpy, x)
+	analytic := make([]float64, n)
+	grad(xCpy, analytic)
+	if !floats.Equal(xCpy, x) {
+		t.Errorf("%v: x modified during call to grad", name)
+		return
+	}
+	fd := make([]float64, n)
+	fdGradientRelative(f, xCpy, fd, s.fdStep)
+	if d := (Tolerance{Abs: absTol, Rel: relTol}).diff(fd, analytic); d != "" {
+		t.Errorf("%v: gradient doesn't match finite difference: %v%v", name, d, gradientSweep(scalar(f), xCpy, true, analytic))
+	}
+}
+
+// complexStep is the imaginary step used by complex-step differentiation. It can be
+// tiny because there is no subtractive cancellation.
+const complexStep = 1e-20
+
+// ComplexObjective is a training objective that can also be evaluated at complex
+// parameters. ObjComplex must be the analytic extension of the objective, written
+// with the same arithmetic on complex128 (avoiding abs and comparisons on the
+// imaginary part, which are not analytic).
+type ComplexObjective interface {
+	ObjGrader
+	ObjComplex(parameters []complex128) complex128
+}
+
+// CheckComplexStep checks the gradient of obj at params using complex-step
+// differentiation, df/dx_i = Im f(x + i·h·e_i) / h, which is accurate to near machine
+// precision for any small h, so tol can be far tighter than in CheckObjectiveGrad.
+// params is not modified.
+func CheckComplexStep(t *testing.T, obj ComplexObjective, params []float64, tol float64, name string) {
+	n := len(params)
+	x := make([]float64, n)
+	copy(x, params)
+	analytic := make([]float64, n)
+	obj.ObjGrad(x, analytic)
+	if !floats.Equal(x, params) {
+		t.Errorf("%v: parameters modified during call to ObjGrad", name)
+		return
+	}
+	z := make([]complex128, n)
+	for i, v := range params {
+		z[i] = complex(v, 0)
+	}
+	cs := make([]float64, n)
+	for i := range z {
+		z[i] = complex(params[i], complexStep)
+		cs[i] = imag(obj.ObjComplex(z)) / complexStep
+		z[i] = complex(params[i], 0)
+	}
+	if d := (Tolerance{Abs: tol, Rel: tol}).diff(cs, analytic); d != "" {
+		t.Errorf("%v: gradient doesn't match complex step: %v", name, d)
+	}
+}
+
+// CheckDirectionalGrad checks the gradient of obj at params along nDirections random
+// unit directions, comparing the directional derivative g·d with a central finite
+// difference of the objective along d. It needs only 2*nDirections+1 evaluations
+// regardless of the number of parameters, making gradient checks feasible for models
+// with millions of parameters where CheckObjectiveGrad is too slow. params is not
+// modified. The step size can be set with the FDStep option. On failure the error at
+// a sweep of step sizes along the direction is reported.
+func CheckDirectionalGrad(t *testing.T, obj ObjGrader, params []float64, nDirections int, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := len(params)
+	if n == 0 {
+		return
+	}
+	grad := make([]float64, n)
+	x := make([]float64, n)
+	copy(x, params)
+	obj.ObjGrad(x, grad)
+	if !floats.Equal(x, params) {
+		t.Errorf("%v: parameters modified during call to ObjGrad", name)
+		return
+	}
+	d := make([]float64, n)
+	scratch := make([]float64, n)
+	// along is the objective at params + α·d as a function of α
+	along := scalar(func(alpha []float64) float64 {
+		for i := range x {
+			x[i] = params[i] + alpha[0]*d[i]
+		}
+		return obj.ObjGrad(x, scratch)
+	})
+	origin := []float64{0}
+	for k := 0; k < nDirections; k++ {
+		for i := range d {
+			d[i] = rnd.NormFloat64()
+		}
+		floats.Scale(1/floats.Norm(d, 2), d)
+		// The values of an objective always have the right size
+		fd, _ := fdJacobianSteps(along, origin, 1, fdSteps(origin, s.fdStep, false))
+		analytic := floats.Dot(grad, d)
+		if !floats.EqualWithinAbsOrRel(fd.At(0, 0), analytic, tol, tol) {
+			t.Errorf("%v: directional derivative doesn't match along direction %v: Finite Difference: %v, Analytic: %v%v", name, k, fd.At(0, 0), analytic, gradientSweep(along, origin, false, []float64{analytic}))
+			return
+		}
+	}
+}
+
+const nHessVecTests = 5
+
+// HessVecer is a training objective that can compute Hessian-vector products without
+// forming the Hessian. HessVec stores the product of the Hessian at parameters with v
+// in hv.
+type HessVecer interface {
+	ObjGrader
+	HessVec(parameters, v, hv []float64)
+}
+
+// CheckHessVec checks Hessian-vector products of obj at params along random vectors v
+// against the central finite difference of the gradient along v,
+// (∇f(x+hv) - ∇f(x-hv)) / 2h. It checks that HessVec does not modify params or v.
+// params is not modified. The step size can be set with the FDStep option. On failure
+// the error at a sweep of step sizes along v is reported.
+func CheckHessVec(t *testing.T, obj HessVecer, params []float64, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := len(params)
+	x := make([]float64, n)
+	v := make([]float64, n)
+	vCpy := make([]float64, n)
+	hv := make([]float64, n)
+	fd := make([]float64, n)
+	// along is the gradient at params + α·v as a function of α
+	along := pointwise(func(alpha, g []float64) error {
+		for i := range x {
+			x[i] = params[i] + alpha[0]*v[i]
+		}
+		obj.ObjGrad(x, g)
+		return nil
+	})
+	origin := []float64{0}
+	for k := 0; k < nHessVecTests; k++ {
+		for i := range v {
+			v[i] = rnd.NormFloat64()
+		}
+		copy(vCpy, v)
+		copy(x, params)
+		obj.HessVec(x, v, hv)
+		if !floats.Equal(x, params) {
+			t.Errorf("%v: parameters modified during call to HessVec", name)
+			return
+		}
+		if !floats.Equal(v, vCpy) {
+			t.Errorf("%v: vector modified during call to HessVec", name)
+			return
+		}
+		// The gradients always have the right size
+		jac, _ := fdJacobianSteps(along, origin, n, fdSteps(origin, s.fdStep, false))
+		jac.Col(fd, 0)
+		if d := (Tolerance{Abs: tol, Rel: tol}).diff(fd, hv); d != "" {
+			t.Errorf("%v: Hessian-vector product doesn't match finite difference along random vector %v: %v%v", name, k, d, stepSweep(along, origin, false, mat64.NewDense(n, 1, hv)))
+			return
+		}
+	}
+}
+
+// BatchObjGrader is a training objective that can be evaluated both on a whole data
+// set and on single samples. Both methods return the objective at parameters and
+// store its gradient with respect to the parameters in derivative. A nil weights in
+// ObjGradBatch means every sample has weight one.
+type BatchObjGrader interface {
+	ObjGradBatch(parameters []float64, inputs, outputs [][]float64, weights, derivative []float64) float64
+	ObjGradSample(parameters, input, output []float64, weight float64, derivative []float64) float64
+}
+
+// Reduction is how a batch objective combines the per-sample objectives
+type Reduction int
+
+const (
+	// Sum is the sum of the per-sample objectives
+	Sum Reduction = iota
+	// Mean is the weighted mean of the per-sample objectives, the sum divided by the
+	// total weight
+	Mean
+)
+
+// CheckBatchGrad checks that the objective and gradient of obj on the whole data set
+// equal the reduction of the per-sample objectives and gradients, catching
+// disagreements in averaging convention between batch and streaming training paths.
+// A nil weights gives every sample weight one. params is not modified.
+func CheckBatchGrad(t *testing.T, obj BatchObjGrader, params []float64, inputs, outputs [][]float64, weights []float64, reduction Reduction, tol float64, name string) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	if weights != nil && len(weights) != len(inputs) {
+		panic("weights and inputs have different number of rows")
+	}
+	n := len(params)
+	x := make([]float64, n)
+	copy(x, params)
+	batchGrad := make([]float64, n)
+	batch := obj.ObjGradBatch(x, inputs, outputs, weights, batchGrad)
+	if !floats.Equal(x, params) {
+		t.Errorf("%v: parameters modified during call to ObjGradBatch", name)
+		return
+	}
+
+	sampleGrad := make([]float64, n)
+	sumGrad := make([]float64, n)
+	var sum, totalWeight float64
+	for i := range inputs {
+		w := 1.0
+		if weights != nil {
+			w = weights[i]
+		}
+		sum += obj.ObjGradSample(x, inputs[i], outputs[i], w, sampleGrad)
+		floats.Add(sumGrad, sampleGrad)
+		totalWeight += w
+	}
+	switch reduction {
+	default:
+		panic("regtest: unknown reduction")
+	case Sum:
+	case Mean:
+		sum /= totalWeight
+		floats.Scale(1/totalWeight, sumGrad)
+	}
+
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	if !cmp.equal(batch, sum) {
+		t.Errorf("%v: batch objective %v doesn't match the reduced per-sample objectives %v", name, batch, sum)
+	}
+	if d := cmp.diff(sumGrad, batchGrad); d != "" {
+		t.Errorf("%v: batch gradient doesn't match the reduced per-sample gradients: %v", name, d)
+	}
+}
+
+// CheckZeroGradAtMinimizer checks that the gradient of obj at a known analytic
+// minimizer, such as the ordinary least squares coefficients of a data set, is zero
+// to within tol in every coordinate. It is a strong end-to-end test that the objective
+// and its gradient agree with the closed form. For ill-conditioned problems, tol can
+// be scaled by the ConditionNumber of the design matrix. minimizer is not modified.
+func CheckZeroGradAtMinimizer(t *testing.T, obj ObjGrader, minimizer []float64, tol float64, name string) {
+	x := make([]float64, len(minimizer))
+	copy(x, minimizer)
+	grad := make([]float64, len(x))
+	obj.ObjGrad(x, grad)
+	if !floats.Equal(x, minimizer) {
+		t.Errorf("%v: parameters modified during call to ObjGrad", name)
+		return
+	}
+	zero := make([]float64, len(grad))
+	if d := (Tolerance{Abs: tol}).diff(zero, grad); d != "" {
+		t.Errorf("%v: gradient at the minimizer is not zero: %v", name, d)
+	}
+}
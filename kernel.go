@@ -0,0 +1,136 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+const (
+	nKernelTests  = 20
+	nKernelPoints = 15
+	kernelSymTol  = 1e-14
+	kernelPSDTol  = 1e-10
+)
+
+// Kerneler is a kernel function between two points of the same dimension
+type Kerneler interface {
+	Kernel(x, y []float64) float64
+}
+
+// TestKernel tests that a kernel is a valid covariance function on random points
+// of dimension dim. It checks that k(x,y) == k(y,x), that k(x,x) >= 0, and that
+// the Gram matrix on sets of random points is positive semi-definite. Symmetry is
+// checked to within kernelSymTol unless the Tol option is given.
+func TestKernel(t *testing.T, k Kerneler, dim int, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	symTol := s.deterministicTol(kernelSymTol)
+	x := make([]float64, dim)
+	y := make([]float64, dim)
+	for i := 0; i < nKernelTests; i++ {
+		for j := 0; j < dim; j++ {
+			x[j] = rnd.NormFloat64()
+			y[j] = rnd.NormFloat64()
+		}
+		kxy := k.Kernel(x, y)
+		kyx := k.Kernel(y, x)
+		if !symTol.equal(kxy, kyx) {
+			t.Errorf("%v: kernel is not symmetric. k(x,y) = %v, k(y,x) = %v", name, kxy, kyx)
+			return
+		}
+		kxx := k.Kernel(x, x)
+		if kxx < 0 || math.IsNaN(kxx) {
+			t.Errorf("%v: k(x,x) is negative or NaN. Found %v", name, kxx)
+			return
+		}
+	}
+
+	points := make([][]float64, nKernelPoints)
+	for i := range points {
+		points[i] = make([]float64, dim)
+	}
+	gram := mat64.NewDense(nKernelPoints, nKernelPoints, nil)
+	gramT := mat64.NewDense(nKernelPoints, nKernelPoints, nil)
+	for test := 0; test < nKernelTests; test++ {
+		for i := range points {
+			for j := range points[i] {
+				points[i][j] = rnd.NormFloat64()
+			}
+		}
+		for i := 0; i < nKernelPoints; i++ {
+			for j := 0; j < nKernelPoints; j++ {
+				v := k.Kernel(points[i], points[j])
+				gram.Set(i, j, v)
+				gramT.Set(j, i, v)
+			}
+		}
+		if !CheckMatricesEqual(t, gramT, gram, symTol, name+": Gram matrix against its transpose") {
+			return
+		}
+		d := mat64.Eigen(gram, math.Pow(2, -52)).D()
+		var maxEig float64
+		for i := 0; i < nKernelPoints; i++ {
+			maxEig = math.Max(maxEig, math.Abs(d.At(i, i)))
+		}
+		for i := 0; i < nKernelPoints; i++ {
+			if d.At(i, i) < -kernelPSDTol*math.Max(1, maxEig) {
+				t.Errorf("%v: Gram matrix is not positive semi-definite. Found eigenvalue %v", name, d.At(i, i))
+				return
+			}
+		}
+	}
+}
+
+// HyperKerneler is a kernel with hyperparameters, such as a log-lengthscale and a
+// log-variance, exposed through ParameterGetterSetter. DerivHyper stores the
+// derivative of Kernel(x, y) with respect to each hyperparameter in deriv.
+type HyperKerneler interface {
+	Kerneler
+	ParameterGetterSetter
+	DerivHyper(x, y, deriv []float64)
+}
+
+// CheckHyperDeriv checks DerivHyper against central finite differences of Kernel with
+// respect to the hyperparameters, at random hyperparameters near the current ones and
+// random pairs of points of dimension dim. The hyperparameters are restored before
+// returning. The step size can be set with the FDStep option. On failure the error at a
+// sweep of step sizes is reported.
+func CheckHyperDeriv(t *testing.T, k HyperKerneler, dim int, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := k.NumParameters()
+	orig := k.Parameters(nil)
+	defer k.SetParameters(orig)
+	hyper := make([]float64, n)
+	x := make([]float64, dim)
+	y := make([]float64, dim)
+	deriv := make([]float64, n)
+	fd := make([]float64, n)
+	f := func(h []float64) float64 {
+		k.SetParameters(h)
+		return k.Kernel(x, y)
+	}
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	for i := 0; i < nKernelTests; i++ {
+		for j := range hyper {
+			hyper[j] = orig[j] + rnd.NormFloat64()
+		}
+		for j := range x {
+			x[j] = rnd.NormFloat64()
+			y[j] = rnd.NormFloat64()
+		}
+		if i%4 == 0 {
+			// The derivative at coincident points is often special-cased
+			copy(y, x)
+		}
+		k.SetParameters(hyper)
+		k.DerivHyper(x, y, deriv)
+		fdGradient(f, hyper, fd, s.fdStep)
+		if d := cmp.diff(fd, deriv); d != "" {
+			t.Errorf("%v: hyperparameter derivative doesn't match finite difference at random hyperparameters %v: %v%v", name, i, d, gradientSweep(scalar(f), hyper, false, deriv))
+			return
+		}
+	}
+}
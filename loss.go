@@ -0,0 +1,239 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+const (
+	maxLossDim   = 5
+	nLossTests   = 20
+	lossZeroTol  = 1e-14
+	lossEqualTol = 1e-14
+)
+
+// Losser is a loss function between a prediction and the true value.
+// LossDeriv returns the loss and stores the derivative of the loss with respect
+// to the prediction in derivative, which must have the same length as prediction.
+type Losser interface {
+	Loss(prediction, truth []float64) float64
+	LossDeriv(prediction, truth, derivative []float64) float64
+}
+
+// TestLossFunction tests a loss function on random prediction/truth pairs of several
+// dimensions. It checks that the loss is nonnegative and zero at a perfect prediction,
+// that LossDeriv agrees with Loss and fills a preallocated derivative, that a nil or
+// wrong-length derivative panics, that the arguments are not modified, and that the
+// derivative matches finite difference with the step and tolerance set by the FDStep
+// and FDTol options.
+func TestLossFunction(t *testing.T, l Losser, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	// Pass the seed on so that the one logged here reproduces CheckLossDeriv
+	opts = append(opts[:len(opts):len(opts)], Seed(s.seed))
+	for dim := 1; dim <= maxLossDim; dim++ {
+		prediction := make([]float64, dim)
+		truth := make([]float64, dim)
+		predCpy := make([]float64, dim)
+		truthCpy := make([]float64, dim)
+		derivative := make([]float64, dim)
+		for i := 0; i < nLossTests; i++ {
+			for j := 0; j < dim; j++ {
+				prediction[j] = rnd.NormFloat64()
+				truth[j] = rnd.NormFloat64()
+			}
+			copy(predCpy, prediction)
+			copy(truthCpy, truth)
+
+			loss := l.Loss(prediction, truth)
+			if loss < 0 || math.IsNaN(loss) {
+				t.Errorf("%v: loss is negative or NaN for dim %v. Found %v", name, dim, loss)
+				return
+			}
+			if !floats.Equal(prediction, predCpy) || !floats.Equal(truth, truthCpy) {
+				t.Errorf("%v: arguments modified during call to Loss", name)
+				return
+			}
+
+			for j := range derivative {
+				derivative[j] = math.NaN()
+			}
+			lossDeriv := l.LossDeriv(prediction, truth, derivative)
+			if math.Abs(loss-lossDeriv) > lossEqualTol*math.Max(1, math.Abs(loss)) {
+				t.Errorf("%v: Loss and LossDeriv return different losses for dim %v. Loss: %v, LossDeriv: %v", name, dim, loss, lossDeriv)
+				return
+			}
+			if floats.HasNaN(derivative) {
+				t.Errorf("%v: LossDeriv did not fill the derivative for dim %v", name, dim)
+				return
+			}
+			if !floats.Equal(prediction, predCpy) || !floats.Equal(truth, truthCpy) {
+				t.Errorf("%v: arguments modified during call to LossDeriv", name)
+				return
+			}
+
+			zero := l.Loss(truth, truth)
+			if math.Abs(zero) > lossZeroTol {
+				t.Errorf("%v: loss is not zero for a perfect prediction for dim %v. Found %v", name, dim, zero)
+				return
+			}
+		}
+
+		f := func(derivative []float64) func() {
+			return func() {
+				l.LossDeriv(prediction, truth, derivative)
+			}
+		}
+		if !panics(f(nil)) {
+			t.Errorf("%v: LossDeriv did not panic with a nil derivative", name)
+		}
+		if !panics(f(make([]float64, dim+1))) {
+			t.Errorf("%v: LossDeriv did not panic with a derivative too long", name)
+		}
+		if dim > 1 && !panics(f(make([]float64, dim-1))) {
+			t.Errorf("%v: LossDeriv did not panic with a derivative too short", name)
+		}
+	}
+
+	CheckLossDeriv(t, l, s.fdTol, name, opts...)
+}
+
+// CheckLossDeriv checks that the derivative returned by LossDeriv matches central
+// finite differences of Loss at random prediction/truth pairs of several dimensions.
+// The step size can be set with the FDStep option. On failure the error at a sweep of
+// step sizes is reported.
+func CheckLossDeriv(t *testing.T, l Losser, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	h := s.fdStep
+	cmp := Tolerance{Abs: tol, Rel: tol}
+	for dim := 1; dim <= maxLossDim; dim++ {
+		prediction := make([]float64, dim)
+		truth := make([]float64, dim)
+		derivative := make([]float64, dim)
+		fdDerivative := make([]float64, dim)
+		for i := 0; i < nLossTests; i++ {
+			for j := 0; j < dim; j++ {
+				prediction[j] = rnd.NormFloat64()
+				truth[j] = rnd.NormFloat64()
+			}
+			l.LossDeriv(prediction, truth, derivative)
+			f := func(p []float64) float64 { return l.Loss(p, truth) }
+			fdGradient(f, prediction, fdDerivative, h)
+			if d := cmp.diff(fdDerivative, derivative); d != "" {
+				t.Errorf("%v: deriv doesn't match finite difference for dim %v: %v%v", name, dim, d, gradientSweep(scalar(f), prediction, false, derivative))
+				return
+			}
+		}
+	}
+}
+
+// secondDiffStep is the step of the second difference in CheckLossConvex. It is larger
+// than the first difference step since rounding error is divided by its square.
+const secondDiffStep = 1e-4
+
+// CheckLossConvex spot checks that a loss declared convex in the prediction is convex,
+// catching sign errors in custom losses. At random pairs of predictions a and b and a
+// random λ in [0, 1] it checks that L(λa + (1-λ)b) <= λL(a) + (1-λ)L(b), with slack
+// tol relative to the larger side, and that the central second difference along a
+// random direction is at least -tol.
+func CheckLossConvex(t *testing.T, l Losser, tol float64, name string, opts ...Option) {
+	rnd := newSettings(opts).rand(t)
+	h := secondDiffStep
+	for dim := 1; dim <= maxLossDim; dim++ {
+		truth := make([]float64, dim)
+		a := make([]float64, dim)
+		b := make([]float64, dim)
+		mid := make([]float64, dim)
+		dir := make([]float64, dim)
+		for i := 0; i < nLossTests; i++ {
+			for j := 0; j < dim; j++ {
+				truth[j] = rnd.NormFloat64()
+				a[j] = rnd.NormFloat64()
+				b[j] = rnd.NormFloat64()
+				dir[j] = rnd.NormFloat64()
+			}
+			lambda := rnd.Float64()
+			for j := range mid {
+				mid[j] = lambda*a[j] + (1-lambda)*b[j]
+			}
+			lo := l.Loss(mid, truth)
+			hi := lambda*l.Loss(a, truth) + (1-lambda)*l.Loss(b, truth)
+			if lo > hi+tol*math.Max(1, math.Max(math.Abs(lo), math.Abs(hi))) {
+				t.Errorf("%v: convexity violated for dim %v between %v and %v at λ = %v: loss %v above chord %v", name, dim, a, b, lambda, lo, hi)
+				return
+			}
+
+			floats.Scale(1/floats.Norm(dir, 2), dir)
+			for j := range mid {
+				mid[j] = a[j] + h*dir[j]
+			}
+			plus := l.Loss(mid, truth)
+			for j := range mid {
+				mid[j] = a[j] - h*dir[j]
+			}
+			minus := l.Loss(mid, truth)
+			second := (plus - 2*l.Loss(a, truth) + minus) / (h * h)
+			if second < -tol {
+				t.Errorf("%v: negative second derivative %v for dim %v at %v along %v", name, second, dim, a, dir)
+				return
+			}
+		}
+	}
+}
+
+// nSubgradientDirections is the number of random directions along which
+// CheckSubgradient tests each derivative
+const nSubgradientDirections = 5
+
+// CheckSubgradient checks the derivative of a convex but possibly non-smooth loss,
+// such as the hinge, absolute or quantile loss, without differencing across kinks.
+// It checks that the returned derivative g is a subgradient: by convexity, along any
+// direction d and for any step h, g·d lies between the backward secant slope
+// (L(p) - L(p-hd))/h and the forward secant slope (L(p+hd) - L(p))/h. This is checked,
+// with slack tol, along random directions at random predictions and at predictions
+// equal to the truth, where many non-smooth losses have their kink. The step size can
+// be set with the FDStep option.
+func CheckSubgradient(t *testing.T, l Losser, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	h := s.fdStep
+	for dim := 1; dim <= maxLossDim; dim++ {
+		prediction := make([]float64, dim)
+		truth := make([]float64, dim)
+		derivative := make([]float64, dim)
+		dir := make([]float64, dim)
+		step := make([]float64, dim)
+		for i := 0; i < nLossTests; i++ {
+			for j := 0; j < dim; j++ {
+				truth[j] = rnd.NormFloat64()
+				prediction[j] = rnd.NormFloat64()
+			}
+			if i%2 == 1 {
+				copy(prediction, truth)
+			}
+			loss := l.LossDeriv(prediction, truth, derivative)
+			for k := 0; k < nSubgradientDirections; k++ {
+				for j := range dir {
+					dir[j] = rnd.NormFloat64()
+				}
+				floats.Scale(1/floats.Norm(dir, 2), dir)
+				for j := range step {
+					step[j] = prediction[j] + h*dir[j]
+				}
+				forward := (l.Loss(step, truth) - loss) / h
+				for j := range step {
+					step[j] = prediction[j] - h*dir[j]
+				}
+				backward := (loss - l.Loss(step, truth)) / h
+				gd := floats.Dot(derivative, dir)
+				if gd > forward+tol || gd < backward-tol {
+					t.Errorf("%v: derivative %v is not a subgradient for dim %v at prediction %v, truth %v: along %v the directional derivative is %v, secant slopes are %v and %v", name, derivative, dim, prediction, truth, dir, gd, backward, forward)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package regtest
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Option configures optional behavior of the test helpers that accept it.
+type Option func(*settings)
+
+// settings holds the configuration built from a list of Options
+type settings struct {
+	fdStep      float64
+	fdTol       float64
+	triangle    bool
+	allocChecks bool
+
+	gradRegimes []float64
+
+	batch    Trainer
+	batchTol float64
+
+	expectedDims func(nSamples int) (numParameters, inputDim, outputDim int)
+
+	tol           Tolerance
+	tolSet        bool
+	roundTrip     Tolerance
+	roundTripSet  bool
+	stochastic    Tolerance
+	stochasticSet bool
+
+	structure StructureFunc
+
+	seed   int64
+	seeded bool
+}
+
+func newSettings(opts []Option) *settings {
+	s := &settings{
+		fdStep: fdStep,
+		fdTol:  fdTol,
+
+		gradRegimes: defaultGradRegimes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if !s.roundTripSet {
+		s.roundTrip = s.tol
+	}
+	return s
+}
+
+// FDStep sets the step size used by finite difference approximations.
+func FDStep(h float64) Option {
+	if h <= 0 {
+		panic("regtest: finite difference step must be positive")
+	}
+	return func(s *settings) {
+		s.fdStep = h
+	}
+}
+
+// FDTol sets the tolerance used by the suites that compare an analytic derivative with
+// finite differences and take no tolerance argument, such as TestRegularizer.
+func FDTol(tol float64) Option {
+	if tol < 0 {
+		panic("regtest: finite difference tolerance must not be negative")
+	}
+	return func(s *settings) {
+		s.fdTol = tol
+	}
+}
+
+// GradRegimes sets the scales of the random parameter vectors at which
+// CheckObjectiveGrad checks the gradient in addition to params and its perturbations.
+// With no scales only params and its perturbations are checked, for objectives whose
+// valid domain is bounded, such as log or probability parameters.
+func GradRegimes(scales ...float64) Option {
+	for _, scale := range scales {
+		if scale <= 0 {
+			panic("regtest: gradient regime scale must be positive")
+		}
+	}
+	scales = append([]float64(nil), scales...)
+	return func(s *settings) {
+		s.gradRegimes = scales
+	}
+}
+
+// Triangle enables checking the triangle inequality in TestDistancer.
+func Triangle() Option {
+	return func(s *settings) {
+		s.triangle = true
+	}
+}
+
+// AllocChecks makes Conforms also run TestParametersNoAlloc. It is off by default,
+// since allocation is a performance property rather than part of the Parameters
+// contract, and correct models may allocate.
+func AllocChecks() Option {
+	return func(s *settings) {
+		s.allocChecks = true
+	}
+}
+
+// MatchBatch makes TestOnlineTrainer train batch on the full data set with Train
+// and check that its parameters match the incrementally trained model to within tol.
+// batch should be a fresh model configured identically to the online model. A
+// tolerance set for the Stochastic profile overrides tol.
+func MatchBatch(batch Trainer, tol float64) Option {
+	return func(s *settings) {
+		s.batch = batch
+		s.batchTol = tol
+	}
+}
+
+// ExpectedDims declares how NumParameters, InputDim and OutputDim depend on the number
+// of training samples, for models (e.g. kernel methods) whose size grows with the data.
+// It is used by TestDimsStable in place of requiring the dimensions to be unchanged.
+func ExpectedDims(f func(nSamples int) (numParameters, inputDim, outputDim int)) Option {
+	return func(s *settings) {
+		s.expectedDims = f
+	}
+}
+
+// Tol sets the tolerance used by the comparisons of values that a correct model
+// computes identically, such as a clone against the original, PredictBatch against
+// Predict, or a parameter round trip. By default these comparisons are exact. A
+// tolerance is useful for models that re-parameterize internally or whose arithmetic
+// is reordered between code paths, for example by SIMD kernels. Checks that inputs
+// are not modified stay exact.
+func Tol(tol Tolerance) Option {
+	return func(s *settings) {
+		s.tol = tol
+		s.tolSet = true
+	}
+}
+
+// RoundTripTol sets the absolute and relative tolerances used when comparing the
+// parameters given to SetParameters with those returned by Parameters, overriding
+// Tol for that comparison. A tolerance is useful for models that legitimately
+// re-parameterize internally, for example storing the log of a scale parameter.
+func RoundTripTol(abs, rel float64) Option {
+	return ProfileTol(Exact, Tolerance{Abs: abs, Rel: rel})
+}
+
+// Profile is a class of checks that share a tolerance, so that one run can be strict
+// where a model should be exact and loose where it is only statistically reproducible.
+type Profile int
+
+const (
+	// Exact checks compare parameters that should survive a round trip unchanged,
+	// such as through SetParameters and Parameters, serialization or a torn-read
+	// check. If no tolerance is set for Exact, the Deterministic one is used.
+	Exact Profile = iota
+	// Deterministic checks compare values that a correct model computes identically
+	// along different code paths, such as a clone against the original or
+	// PredictBatch against Predict. Its tolerance is the one set by Tol.
+	Deterministic
+	// Stochastic checks compare the results of separate training runs, such as
+	// incremental against batch training (MatchBatch) and the weighted training
+	// semantics of TestWeightedTrain. If no tolerance is set for Stochastic, the
+	// tolerance given to those helpers is used.
+	Stochastic
+)
+
+// ProfileTol sets the tolerance of the checks in profile p.
+func ProfileTol(p Profile, tol Tolerance) Option {
+	switch p {
+	default:
+		panic("regtest: unknown tolerance profile")
+	case Exact:
+		return func(s *settings) {
+			s.roundTrip = tol
+			s.roundTripSet = true
+		}
+	case Deterministic:
+		return Tol(tol)
+	case Stochastic:
+		return func(s *settings) {
+			s.stochastic = tol
+			s.stochasticSet = true
+		}
+	}
+}
+
+// deterministicTol returns the Deterministic tolerance, or an absolute and relative
+// tolerance of def if none was set, for the checks whose default is not exact
+func (s *settings) deterministicTol(def float64) Tolerance {
+	if s.tolSet {
+		return s.tol
+	}
+	return Tolerance{Abs: def, Rel: def}
+}
+
+// stochasticTol returns the Stochastic tolerance, or an absolute and relative
+// tolerance of def if none was set
+func (s *settings) stochasticTol(def float64) Tolerance {
+	if s.stochasticSet {
+		return s.stochastic
+	}
+	return Tolerance{Abs: def, Rel: def}
+}
+
+// Structure declares a structural relationship between the dimensions of a model,
+// which TestTrain and TestDimsStable check before and after training.
+func Structure(f StructureFunc) Option {
+	return func(s *settings) {
+		s.structure = f
+	}
+}
+
+// Seed sets the seed of the random source used to generate test points and
+// parameters. Without it a seed is chosen from the clock, and it is logged if the
+// test fails so that the failure can be reproduced.
+func Seed(seed int64) Option {
+	return func(s *settings) {
+		s.seed = seed
+		s.seeded = true
+	}
+}
+
+// rand returns a new random source for the configured seed, choosing one from the
+// clock if none was set. The seed is logged when t completes if t had not already
+// failed when rand was called but has failed since.
+func (s *settings) rand(t testing.TB) *rand.Rand {
+	if !s.seeded {
+		s.seed = time.Now().UnixNano()
+		s.seeded = true
+	}
+	seed := s.seed
+	failed := t.Failed()
+	t.Cleanup(func() {
+		if !failed && t.Failed() {
+			t.Logf("random seed %d; rerun with regtest.Seed(%d) to reproduce", seed, seed)
+		}
+	})
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,614 @@
+package regtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gonum/floats"
+)
+
+// TestParameterAliasing tests that the slice returned by Parameters(nil) does not alias
+// the model. It mutates the returned slice and checks that Parameters and the
+// predictions on random inputs are unchanged.
+func TestParameterAliasing(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if p.NumParameters() == 0 {
+		return
+	}
+	probes := randomProbes(nProbes, p.InputDim(), rnd)
+	before, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	params := p.Parameters(nil)
+	orig := make([]float64, len(params))
+	copy(orig, params)
+	for i := range params {
+		params[i] = rnd.NormFloat64()
+	}
+	if d := s.tol.diff(orig, p.Parameters(nil)); d != "" {
+		t.Errorf("%v: Modifying the return from Parameters(nil) modified the underlying parameters: %v", name, d)
+	}
+	after, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(before, after); d != "" {
+		t.Errorf("%v: Modifying the return from Parameters(nil) changed the predictions: %v", name, d)
+	}
+}
+
+// TestSetParametersCopies tests that SetParameters copies its input rather than
+// retaining it. After SetParameters, it mutates the caller's slice and checks that
+// Parameters and the predictions on random inputs are unchanged.
+func TestSetParametersCopies(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if p.NumParameters() == 0 {
+		return
+	}
+	probes := randomProbes(nProbes, p.InputDim(), rnd)
+	params := randomParameters(p, rnd)
+	orig := make([]float64, len(params))
+	copy(orig, params)
+	p.SetParameters(params)
+	before, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	for i := range params {
+		params[i] = rnd.NormFloat64()
+	}
+	if d := s.roundTrip.diff(orig, p.Parameters(nil)); d != "" {
+		t.Errorf("%v: Modifying the input to SetParameters after the call modified the underlying parameters: %v", name, d)
+	}
+	after, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(before, after); d != "" {
+		t.Errorf("%v: Modifying the input to SetParameters after the call changed the predictions: %v", name, d)
+	}
+}
+
+const nStressCycles = 500
+
+// wideFloat returns a random float with magnitude between 1e-300 and 1e300
+func wideFloat(rnd *rand.Rand) float64 {
+	v := math.Pow(10, 600*rnd.Float64()-300)
+	if rnd.Intn(2) == 0 {
+		v = -v
+	}
+	return v
+}
+
+// TestParametersStress performs many random SetParameters/Parameters cycles with
+// values spanning magnitudes from 1e-300 to 1e300, and checks that the parameters
+// round trip every time, exactly unless the RoundTripTol or Tol option is given. This
+// catches models that silently quantize, clamp or rescale their parameters.
+func TestParametersStress(t *testing.T, p ParameterGetterSetter, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := p.NumParameters()
+	if n == 0 {
+		return
+	}
+	params := make([]float64, n)
+	dst := make([]float64, n)
+	for i := 0; i < nStressCycles; i++ {
+		for j := range params {
+			params[j] = wideFloat(rnd)
+		}
+		p.SetParameters(params)
+		p.Parameters(dst)
+		for j := range params {
+			if !s.roundTrip.equal(params[j], dst[j]) {
+				t.Errorf("%v: parameter %v did not round trip on cycle %v. Set %v, got %v", name, j, i, params[j], dst[j])
+				return
+			}
+		}
+	}
+}
+
+// NonFiniteContract declares how a model rejects non-finite parameters
+type NonFiniteContract int
+
+const (
+	// PanicOnNonFinite declares that SetParameters panics on NaN or ±Inf
+	PanicOnNonFinite NonFiniteContract = iota
+	// ErrorOnNonFinite declares that the model is a SetParametersErrer and that
+	// SetParametersErr returns an error on NaN or ±Inf
+	ErrorOnNonFinite
+)
+
+// SetParametersErrer is a model that can report an error when setting parameters
+type SetParametersErrer interface {
+	SetParametersErr([]float64) error
+}
+
+// TestNonFiniteParameters tests that setting a parameter to NaN, +Inf or -Inf is rejected
+// according to contract, and that the parameters are unchanged by a rejected call.
+func TestNonFiniteParameters(t *testing.T, p ParameterGetterSetter, contract NonFiniteContract, name string, opts ...Option) {
+	rnd := newSettings(opts).rand(t)
+	n := p.NumParameters()
+	if n == 0 {
+		return
+	}
+	var set func([]float64) error
+	switch contract {
+	case PanicOnNonFinite:
+		set = func(x []float64) error {
+			p.SetParameters(x)
+			return nil
+		}
+	case ErrorOnNonFinite:
+		e, ok := p.(SetParametersErrer)
+		if !ok {
+			t.Errorf("%v: model declares ErrorOnNonFinite but is not a SetParametersErrer", name)
+			return
+		}
+		set = e.SetParametersErr
+	default:
+		panic("regtest: unknown non-finite contract")
+	}
+
+	orig := p.Parameters(nil)
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		params := make([]float64, n)
+		copy(params, orig)
+		params[rnd.Intn(n)] = v
+		var err error
+		panicked := panics(func() { err = set(params) })
+		rejected := false
+		switch contract {
+		case PanicOnNonFinite:
+			rejected = panicked
+			if !panicked {
+				t.Errorf("%v: SetParameters did not panic with a %v parameter", name, v)
+			}
+		case ErrorOnNonFinite:
+			rejected = !panicked && err != nil
+			if panicked {
+				t.Errorf("%v: SetParametersErr panicked with a %v parameter", name, v)
+			} else if err == nil {
+				t.Errorf("%v: SetParametersErr did not return an error with a %v parameter", name, v)
+			}
+		}
+		if rejected && !floats.Equal(p.Parameters(nil), orig) {
+			t.Errorf("%v: Rejected non-finite SetParameters modified the parameters", name)
+		}
+		p.SetParameters(orig)
+	}
+}
+
+// TestFiniteAfterTraining trains tr on the data and checks that Parameters returns
+// only finite values.
+func TestFiniteAfterTraining(t *testing.T, tr Trainer, inputs, outputs [][]float64, name string) {
+	err := tr.Train(inputs, outputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error training: %v", name, err)
+		return
+	}
+	CheckFiniteParameters(t, tr, name)
+}
+
+// CheckFiniteParameters fails if any parameter of p is NaN or ±Inf, reporting the
+// offending indices. It is intended to be called after training, since divergent
+// optimizers often pass the other checks while holding garbage parameters.
+func CheckFiniteParameters(t *testing.T, p ParameterGetterSetter, name string) {
+	var bad []int
+	params := p.Parameters(nil)
+	for i, v := range params {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			bad = append(bad, i)
+		}
+	}
+	if len(bad) == 0 {
+		return
+	}
+	t.Errorf("%v: %v of %v parameters are not finite: %v", name, len(bad), len(params), listed(len(bad), func(k int) string {
+		return fmt.Sprintf("[%v] = %v", bad[k], params[bad[k]])
+	}))
+}
+
+// ParameterNamer is a model that can name each of its parameters
+type ParameterNamer interface {
+	ParameterGetterSetter
+	ParameterNames() []string
+}
+
+// TestParameterNames tests that ParameterNames returns one name per parameter, that
+// the names are unique, and that repeated calls return the same names.
+func TestParameterNames(t *testing.T, p ParameterNamer, name string) {
+	names := p.ParameterNames()
+	if len(names) != p.NumParameters() {
+		t.Errorf("%v: ParameterNames returned %v names, NumParameters is %v", name, len(names), p.NumParameters())
+		return
+	}
+	seen := make(map[string]int, len(names))
+	for i, n := range names {
+		if j, ok := seen[n]; ok {
+			t.Errorf("%v: parameters %v and %v have the same name %q", name, j, i, n)
+			return
+		}
+		seen[n] = i
+	}
+	orig := make([]string, len(names))
+	copy(orig, names)
+	for i := range names {
+		names[i] = ""
+	}
+	again := p.ParameterNames()
+	if len(again) != len(orig) {
+		t.Errorf("%v: ParameterNames is not stable across calls", name)
+		return
+	}
+	for i := range orig {
+		if again[i] != orig[i] {
+			t.Errorf("%v: ParameterNames is not stable across calls. Name %v was %q, then %q", name, i, orig[i], again[i])
+			return
+		}
+	}
+}
+
+// ParameterGetterSetterErr is the error-returning variant of ParameterGetterSetter,
+// for implementations that return errors rather than panicking on bad input
+type ParameterGetterSetterErr interface {
+	NumParameters() int
+	ParametersErr([]float64) ([]float64, error)
+	SetParametersErrer
+}
+
+// TestGetAndSetParametersErr is TestGetAndSetParameters for the error-returning
+// parameter API. Bad lengths must return an error, not panic, and the error message
+// must contain both the expected and the actual length.
+func TestGetAndSetParametersErr(t *testing.T, p ParameterGetterSetterErr, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := p.NumParameters()
+	var nilParam []float64
+	var err error
+	if panics(func() { nilParam, err = p.ParametersErr(nil) }) {
+		t.Errorf("%v: ParametersErr panicked with nil input", name)
+		return
+	}
+	if err != nil {
+		t.Errorf("%v: ParametersErr returned an error with nil input: %v", name, err)
+		return
+	}
+	if len(nilParam) != n {
+		t.Errorf("%v: On nil input, incorrect length returned from ParametersErr()", name)
+		return
+	}
+
+	setParam := make([]float64, n)
+	for i := range setParam {
+		setParam[i] = rnd.NormFloat64()
+	}
+	setCpy := make([]float64, n)
+	copy(setCpy, setParam)
+	if err := p.SetParametersErr(setParam); err != nil {
+		t.Errorf("%v: SetParametersErr returned an error with the correct length: %v", name, err)
+		return
+	}
+	if !floats.Equal(setParam, setCpy) {
+		t.Errorf("%v: Input slice modified during call to SetParametersErr", name)
+	}
+	dst := make([]float64, n)
+	if _, err := p.ParametersErr(dst); err != nil {
+		t.Errorf("%v: ParametersErr returned an error with the correct length: %v", name, err)
+		return
+	}
+	if d := s.roundTrip.diff(setCpy, dst); d != "" {
+		t.Errorf("%v: SetParametersErr followed by ParametersErr don't return the same argument: %v", name, d)
+	}
+
+	lengths := []int{n + 3}
+	if n > 0 {
+		lengths = append(lengths, n-1)
+	}
+	for _, l := range lengths {
+		bad := make([]float64, l)
+		var err error
+		if panics(func() { _, err = p.ParametersErr(bad) }) {
+			t.Errorf("%v: ParametersErr panicked given a slice of length %v", name, l)
+		} else {
+			checkLengthErr(t, err, n, l, "ParametersErr", name)
+		}
+		if panics(func() { err = p.SetParametersErr(bad) }) {
+			t.Errorf("%v: SetParametersErr panicked given a slice of length %v", name, l)
+		} else {
+			checkLengthErr(t, err, n, l, "SetParametersErr", name)
+		}
+	}
+}
+
+// checkLengthErr checks that err is a length mismatch error mentioning both lengths
+func checkLengthErr(t *testing.T, err error, expected, actual int, method, name string) {
+	if err == nil {
+		t.Errorf("%v: %v did not return an error given a slice of length %v", name, method, actual)
+		return
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, strconv.Itoa(expected)) || !strings.Contains(msg, strconv.Itoa(actual)) {
+		t.Errorf("%v: %v error %q does not identify the expected length %v and actual length %v", name, method, msg, expected, actual)
+	}
+}
+
+// TestParametersAffectPredictions sets two different random parameter vectors and
+// checks that the predictions on random inputs change, which catches implementations
+// whose SetParameters is silently a no-op. The parameters are restored before returning.
+func TestParametersAffectPredictions(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if p.NumParameters() == 0 {
+		return
+	}
+	orig := p.Parameters(nil)
+	defer p.SetParameters(orig)
+	probes := randomProbes(nProbes, p.InputDim(), rnd)
+
+	p.SetParameters(randomParameters(p, rnd))
+	first, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	p.SetParameters(randomParameters(p, rnd))
+	second, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	if s.tol.equalPredictions(first, second) {
+		t.Errorf("%v: Predictions did not change when the parameters were changed", name)
+	}
+}
+
+// Bounded is a model whose parameters are restricted to a box. Bounds may be ±Inf.
+type Bounded interface {
+	ParameterGetterSetter
+	ParameterBounds() (lower, upper []float64)
+}
+
+// BoundsContract declares how a Bounded model handles parameters outside its bounds
+type BoundsContract int
+
+const (
+	// PanicOutOfBounds declares that SetParameters panics outside the bounds
+	PanicOutOfBounds BoundsContract = iota
+	// ClampOutOfBounds declares that SetParameters clamps parameters to the bounds
+	ClampOutOfBounds
+)
+
+// TestParameterBounds tests that the bounds have length NumParameters and satisfy
+// lower <= upper, and that setting each parameter beyond each of its finite bounds
+// panics or clamps according to contract. The parameters are restored before returning.
+func TestParameterBounds(t *testing.T, p Bounded, contract BoundsContract, name string) {
+	n := p.NumParameters()
+	lower, upper := p.ParameterBounds()
+	if len(lower) != n || len(upper) != n {
+		t.Errorf("%v: bounds have length %v and %v, NumParameters is %v", name, len(lower), len(upper), n)
+		return
+	}
+	for i := range lower {
+		if !(lower[i] <= upper[i]) {
+			t.Errorf("%v: bounds for parameter %v are not ordered. Lower %v, upper %v", name, i, lower[i], upper[i])
+			return
+		}
+	}
+
+	orig := p.Parameters(nil)
+	defer p.SetParameters(orig)
+	// inside is a parameter vector within the bounds
+	inside := make([]float64, n)
+	for i := range inside {
+		switch {
+		case !math.IsInf(lower[i], 0) && !math.IsInf(upper[i], 0):
+			inside[i] = lower[i] + (upper[i]-lower[i])/2
+		case !math.IsInf(lower[i], 0):
+			inside[i] = lower[i] + 1
+		case !math.IsInf(upper[i], 0):
+			inside[i] = upper[i] - 1
+		}
+	}
+	for i := range inside {
+		for side, bound := range []float64{lower[i], upper[i]} {
+			if math.IsInf(bound, 0) {
+				continue
+			}
+			params := make([]float64, n)
+			copy(params, inside)
+			if side == 0 {
+				params[i] = bound - 1 - math.Abs(bound)
+			} else {
+				params[i] = bound + 1 + math.Abs(bound)
+			}
+			switch contract {
+			case PanicOutOfBounds:
+				p.SetParameters(inside)
+				if !panics(func() { p.SetParameters(params) }) {
+					t.Errorf("%v: SetParameters did not panic with parameter %v set to %v outside the bound %v", name, i, params[i], bound)
+				}
+			case ClampOutOfBounds:
+				p.SetParameters(params)
+				got := p.Parameters(nil)
+				if got[i] != bound {
+					t.Errorf("%v: SetParameters did not clamp parameter %v to the bound %v. Found %v", name, i, bound, got[i])
+				}
+			default:
+				panic("regtest: unknown bounds contract")
+			}
+		}
+	}
+}
+
+const (
+	nLargeRuns     = 10
+	maxLargeAllocs = 2
+)
+
+// TestLargeParameters is an opt-in performance guard for models with very many
+// (e.g. millions of) parameters, which catches accidental quadratic copying. It checks
+// that Parameters and SetParameters each make at most a constant number of allocations,
+// and that a Parameters/SetParameters round trip takes no longer than maxDuration on
+// average. It is skipped in short mode.
+func TestLargeParameters(t *testing.T, p ParameterGetterSetter, maxDuration time.Duration, name string) {
+	if testing.Short() {
+		t.Skipf("%v: skipping large parameter test in short mode", name)
+	}
+	params := p.Parameters(nil)
+	dst := make([]float64, len(params))
+
+	allocs := testing.AllocsPerRun(nLargeRuns, func() { p.Parameters(dst) })
+	if allocs > maxLargeAllocs {
+		t.Errorf("%v: Parameters with %v parameters made %v allocations per call", name, len(params), allocs)
+	}
+	allocs = testing.AllocsPerRun(nLargeRuns, func() { p.Parameters(nil) })
+	if allocs > maxLargeAllocs {
+		t.Errorf("%v: Parameters(nil) with %v parameters made %v allocations per call", name, len(params), allocs)
+	}
+	allocs = testing.AllocsPerRun(nLargeRuns, func() { p.SetParameters(params) })
+	if allocs > maxLargeAllocs {
+		t.Errorf("%v: SetParameters with %v parameters made %v allocations per call", name, len(params), allocs)
+	}
+
+	start := time.Now()
+	for i := 0; i < nLargeRuns; i++ {
+		p.SetParameters(params)
+		p.Parameters(dst)
+	}
+	elapsed := time.Since(start) / nLargeRuns
+	if elapsed > maxDuration {
+		t.Errorf("%v: round trip with %v parameters took %v, limit is %v", name, len(params), elapsed, maxDuration)
+	}
+}
+
+// TestParametersNoAlloc checks that Parameters with a correctly sized destination makes
+// no heap allocations, since optimizers snapshot parameters in hot loops. Conforms runs
+// it only with the AllocChecks option.
+func TestParametersNoAlloc(t *testing.T, p ParameterGetterSetter, name string) {
+	dst := make([]float64, p.NumParameters())
+	allocs := testing.AllocsPerRun(nLargeRuns, func() { p.Parameters(dst) })
+	if allocs != 0 {
+		t.Errorf("%v: Parameters with a correctly sized destination made %v allocations per call", name, allocs)
+	}
+}
+
+// TestPredictPreservesParameters checks that the parameters are bitwise identical before
+// and after a batch of Predict calls on random inputs. Lazily-normalizing models
+// sometimes rewrite their parameters during prediction, which breaks optimizers.
+func TestPredictPreservesParameters(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	rnd := newSettings(opts).rand(t)
+	before := p.Parameters(nil)
+	if _, err := predictAll(p, randomProbes(nPredictTests, p.InputDim(), rnd)); err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	after := p.Parameters(nil)
+	if len(after) != len(before) {
+		t.Errorf("%v: Predict changed the number of parameters from %v to %v", name, len(before), len(after))
+		return
+	}
+	for i := range before {
+		if math.Float64bits(before[i]) != math.Float64bits(after[i]) {
+			t.Errorf("%v: Predict modified parameter %v. Before %v, after %v", name, i, before[i], after[i])
+			return
+		}
+	}
+}
+
+// SingleParameterSetter is a model that can set one parameter at a time
+type SingleParameterSetter interface {
+	ParameterGetterSetter
+	SetParameter(i int, v float64)
+}
+
+// TestSetParameter tests that setting the parameters one index at a time gives the same
+// parameters as a single SetParameters call (and the same predictions on random inputs,
+// if the model is a Predictor), and that out of range indices panic.
+func TestSetParameter(t *testing.T, p SingleParameterSetter, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	n := p.NumParameters()
+	params := randomParameters(p, rnd)
+	p.SetParameters(params)
+	want := p.Parameters(nil)
+	pred, isPred := p.(Predictor)
+	var probes, wantPred [][]float64
+	if isPred {
+		probes = randomProbes(nProbes, pred.InputDim(), rnd)
+		var err error
+		wantPred, err = predictAll(pred, probes)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+	}
+
+	p.SetParameters(randomParameters(p, rnd))
+	for i, v := range params {
+		p.SetParameter(i, v)
+	}
+	if d := s.roundTrip.diff(want, p.Parameters(nil)); d != "" {
+		t.Errorf("%v: Setting parameters one at a time differs from SetParameters: %v", name, d)
+	}
+	if isPred {
+		got, err := predictAll(pred, probes)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		if d := s.tol.diffRows(wantPred, got); d != "" {
+			t.Errorf("%v: Predictions after setting parameters one at a time differ from after SetParameters: %v", name, d)
+		}
+	}
+
+	for _, i := range []int{-1, n} {
+		if !panics(func() { p.SetParameter(i, 0) }) {
+			t.Errorf("%v: SetParameter did not panic with index %v, NumParameters is %v", name, i, n)
+		}
+	}
+}
+
+// TestSetParametersIdempotent checks that calling SetParameters twice with the same
+// vector leaves the model in the same state as calling it once: Parameters and the
+// predictions on a fixed set of random inputs must match, exactly unless the Tol
+// option is given. This catches models that accumulate state (e.g. momentum buffers)
+// inside SetParameters.
+func TestSetParametersIdempotent(t *testing.T, p ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	probes := randomProbes(nProbes, p.InputDim(), rnd)
+	params := randomParameters(p, rnd)
+
+	p.SetParameters(params)
+	once := p.Parameters(nil)
+	oncePred, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	p.SetParameters(params)
+	if d := s.roundTrip.diff(once, p.Parameters(nil)); d != "" {
+		t.Errorf("%v: Parameters differ after calling SetParameters twice with the same vector: %v", name, d)
+	}
+	twicePred, err := predictAll(p, probes)
+	if err != nil {
+		t.Errorf("%v: Error predicting: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(oncePred, twicePred); d != "" {
+		t.Errorf("%v: Predictions differ after calling SetParameters twice with the same vector: %v", name, d)
+	}
+}
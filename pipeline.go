@@ -0,0 +1,89 @@
+package regtest
+
+import (
+	"testing"
+)
+
+// Transformer maps an input of length InputDim to an output of length OutputDim.
+// As with Predict, a nil output is allocated.
+type Transformer interface {
+	Transform(input, output []float64) ([]float64, error)
+	InputOutputer
+}
+
+// TestPipeline tests a model composed of transform followed by inner. It checks that
+// the dimensions of the pipeline match its components, with InputDim in the
+// pre-transform space, that the pipeline's predictions equal chaining Transform and
+// the inner Predict, and that the pipeline's parameters are the parameters of transform
+// (if it is a ParameterGetterSetter) followed by those of inner, both when getting and
+// setting. The parameters of the pipeline are restored before returning.
+func TestPipeline(t *testing.T, pipe ParameterPredictor, transform Transformer, inner ParameterPredictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if pipe.InputDim() != transform.InputDim() {
+		t.Errorf("%v: pipeline InputDim is %v, transform InputDim is %v", name, pipe.InputDim(), transform.InputDim())
+		return
+	}
+	if transform.OutputDim() != inner.InputDim() {
+		t.Errorf("%v: transform OutputDim is %v, inner InputDim is %v", name, transform.OutputDim(), inner.InputDim())
+		return
+	}
+	if pipe.OutputDim() != inner.OutputDim() {
+		t.Errorf("%v: pipeline OutputDim is %v, inner OutputDim is %v", name, pipe.OutputDim(), inner.OutputDim())
+		return
+	}
+
+	for _, input := range randomProbes(nProbes, pipe.InputDim(), rnd) {
+		want, err := chain(transform, inner, input)
+		if err != nil {
+			t.Errorf("%v: Error predicting with components: %v", name, err)
+			return
+		}
+		got, err := pipe.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting with pipeline: %v", name, err)
+			return
+		}
+		if d := s.tol.diff(want, got); d != "" {
+			t.Errorf("%v: pipeline prediction doesn't match chained components: %v", name, d)
+			return
+		}
+	}
+
+	var transformParams ParameterGetterSetter
+	nTransform := 0
+	if p, ok := transform.(ParameterGetterSetter); ok {
+		transformParams = p
+		nTransform = p.NumParameters()
+	}
+	if pipe.NumParameters() != nTransform+inner.NumParameters() {
+		t.Errorf("%v: pipeline NumParameters is %v, components have %v and %v", name, pipe.NumParameters(), nTransform, inner.NumParameters())
+		return
+	}
+	check := func(when string) {
+		params := pipe.Parameters(nil)
+		if transformParams != nil {
+			if d := s.roundTrip.diff(transformParams.Parameters(nil), params[:nTransform]); d != "" {
+				t.Errorf("%v: %v, pipeline parameters don't start with the transform parameters: %v", name, when, d)
+			}
+		}
+		if d := s.roundTrip.diff(inner.Parameters(nil), params[nTransform:]); d != "" {
+			t.Errorf("%v: %v, pipeline parameters don't end with the inner parameters: %v", name, when, d)
+		}
+	}
+	check("before SetParameters")
+
+	orig := pipe.Parameters(nil)
+	pipe.SetParameters(randomParameters(pipe, rnd))
+	check("after SetParameters")
+	pipe.SetParameters(orig)
+}
+
+// chain predicts input by applying transform followed by inner
+func chain(transform Transformer, inner Predictor, input []float64) ([]float64, error) {
+	transformed, err := transform.Transform(input, nil)
+	if err != nil {
+		return nil, err
+	}
+	return inner.Predict(transformed, nil)
+}
@@ -0,0 +1,411 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/gonum/matrix/mat64"
+
+	"github.com/reggo/common"
+)
+
+const nPredictTests = 20
+
+// TestPredict tests the single-sample Predict contract on random inputs. It checks
+// that a nil output is allocated with the right length, that a non-nil output is
+// used in place, that the input is never modified, and that inputs and outputs of
+// the wrong length are rejected. Only a nil output is allocated; a non-nil output of
+// the wrong length, including an empty one, must be rejected. A bad length may be
+// rejected either by returning an error or by panicking.
+func TestPredict(t *testing.T, p Predictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	inputDim := p.InputDim()
+	outputDim := p.OutputDim()
+
+	input := make([]float64, inputDim)
+	inputCpy := make([]float64, inputDim)
+	for i := 0; i < nPredictTests; i++ {
+		for j := range input {
+			input[j] = rnd.NormFloat64()
+		}
+		copy(inputCpy, input)
+
+		var nilOut []float64
+		var err error
+		f := func() {
+			nilOut, err = p.Predict(input, nil)
+		}
+		if maybe(f) {
+			t.Errorf("%v: Predict panicked with nil output", name)
+			return
+		}
+		if err != nil {
+			t.Errorf("%v: Error predicting with nil output: %v", name, err)
+			return
+		}
+		if len(nilOut) != outputDim {
+			t.Errorf("%v: On nil output, incorrect length returned from Predict. Expected %v, found %v", name, outputDim, len(nilOut))
+			return
+		}
+		if !floats.Equal(input, inputCpy) {
+			t.Errorf("%v: input changed during Predict with nil output", name)
+			return
+		}
+
+		output := make([]float64, outputDim)
+		for j := range output {
+			output[j] = rnd.NormFloat64()
+		}
+		out, err := p.Predict(input, output)
+		if err != nil {
+			t.Errorf("%v: Error predicting with non-nil output: %v", name, err)
+			return
+		}
+		if len(out) != outputDim {
+			t.Errorf("%v: On non-nil output, incorrect length returned from Predict. Expected %v, found %v", name, outputDim, len(out))
+			return
+		}
+		if outputDim > 0 && &out[0] != &output[0] {
+			t.Errorf("%v: Predict did not store the prediction in the provided output", name)
+		}
+		if !floats.Equal(input, inputCpy) {
+			t.Errorf("%v: input changed during Predict with non-nil output", name)
+			return
+		}
+		if d := s.tol.diff(nilOut, out); d != "" {
+			t.Errorf("%v: different answers with nil and non-nil output: %v", name, d)
+			return
+		}
+	}
+
+	output := make([]float64, outputDim)
+	f := func(in, out []float64) func() error {
+		return func() error {
+			_, err := p.Predict(in, out)
+			return err
+		}
+	}
+	if !rejects(f(input, make([]float64, outputDim+1))) {
+		t.Errorf("%v: Predict did not reject an output too long", name)
+	}
+	if outputDim > 0 && !rejects(f(input, make([]float64, outputDim-1))) {
+		t.Errorf("%v: Predict did not reject an output too short", name)
+	}
+	if !rejects(f(make([]float64, inputDim+1), output)) {
+		t.Errorf("%v: Predict did not reject an input too long", name)
+	}
+	if inputDim > 0 && !rejects(f(make([]float64, inputDim-1), output)) {
+		t.Errorf("%v: Predict did not reject an input too short", name)
+	}
+}
+
+// TestPredictBatch tests that PredictBatch on a matrix of inputs gives the same values
+// as calling Predict on each row, both when the output matrix is nil and when it is
+// preallocated. The comparison is exact unless the Tol option is given.
+func TestPredictBatch(t *testing.T, p BatchPredictor, inputs common.RowMatrix, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	nSamples, inputDim := inputs.Dims()
+	if inputDim != p.InputDim() {
+		panic("input Dim doesn't match predictor input dim")
+	}
+	outputDim := p.OutputDim()
+
+	rowOutputs := mat64.NewDense(nSamples, outputDim, nil)
+	input := make([]float64, inputDim)
+	for i := 0; i < nSamples; i++ {
+		inputs.Row(input, i)
+		out, err := p.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting row %v: %v", name, i, err)
+			return
+		}
+		rowOutputs.SetRow(i, out)
+	}
+
+	nilOutputs, err := p.PredictBatch(inputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error batch predicting with nil output: %v", name, err)
+		return
+	}
+	CheckMatricesEqual(t, rowOutputs, nilOutputs, s.tol, name+": PredictBatch with nil output against Predict")
+
+	preOutputs := mat64.NewDense(nSamples, outputDim, nil)
+	for i := 0; i < nSamples; i++ {
+		for j := 0; j < outputDim; j++ {
+			preOutputs.Set(i, j, rnd.NormFloat64())
+		}
+	}
+	_, err = p.PredictBatch(inputs, preOutputs)
+	if err != nil {
+		t.Errorf("%v: Error batch predicting with preallocated output: %v", name, err)
+		return
+	}
+	CheckMatricesEqual(t, rowOutputs, preOutputs, s.tol, name+": PredictBatch with preallocated output against Predict")
+}
+
+// ProbabilisticPredictor is a model that predicts a mean and a variance for each output.
+// As with Predict, nil mean and variance slices are allocated.
+type ProbabilisticPredictor interface {
+	PredictMeanVar(input, mean, variance []float64) ([]float64, []float64, error)
+	InputOutputer
+}
+
+// IntervalPredictor is a model that predicts an interval for each output which
+// contains the true value with probability level.
+type IntervalPredictor interface {
+	PredictInterval(input []float64, level float64, low, high []float64) ([]float64, []float64, error)
+}
+
+// TestProbabilisticPredictor tests the predicted distributions of a trained model on
+// held-out samples, which should be generated with Gaussian noise. It checks that
+// every variance is nonnegative and, for each output, that the fraction of true
+// outputs falling inside the predicted interval is within tol of level. If the model
+// is also an IntervalPredictor its intervals are used and are checked to satisfy
+// low <= mean <= high, otherwise the Gaussian interval mean ± z*sqrt(variance) is used.
+func TestProbabilisticPredictor(t *testing.T, p ProbabilisticPredictor, inputs, outputs [][]float64, level, tol float64, name string) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	if level <= 0 || level >= 1 {
+		panic("level must be between zero and one")
+	}
+	outputDim := p.OutputDim()
+	interval, isInterval := p.(IntervalPredictor)
+	z := math.Sqrt2 * math.Erfinv(level)
+
+	covered := make([]int, outputDim)
+	low := make([]float64, outputDim)
+	high := make([]float64, outputDim)
+	for i := range inputs {
+		mean, variance, err := p.PredictMeanVar(inputs[i], nil, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting distribution for sample %v: %v", name, i, err)
+			return
+		}
+		if len(mean) != outputDim || len(variance) != outputDim {
+			t.Errorf("%v: Wrong length returned from PredictMeanVar for sample %v", name, i)
+			return
+		}
+		for j, v := range variance {
+			if v < 0 || math.IsNaN(v) {
+				t.Errorf("%v: Negative or NaN variance for sample %v output %v: %v", name, i, j, v)
+				return
+			}
+		}
+		if isInterval {
+			_, _, err := interval.PredictInterval(inputs[i], level, low, high)
+			if err != nil {
+				t.Errorf("%v: Error predicting interval for sample %v: %v", name, i, err)
+				return
+			}
+			for j := range low {
+				if !(low[j] <= mean[j] && mean[j] <= high[j]) {
+					t.Errorf("%v: Interval not ordered for sample %v output %v: low %v, mean %v, high %v", name, i, j, low[j], mean[j], high[j])
+					return
+				}
+			}
+		} else {
+			for j := range low {
+				sd := math.Sqrt(variance[j])
+				low[j] = mean[j] - z*sd
+				high[j] = mean[j] + z*sd
+			}
+		}
+		for j, v := range outputs[i] {
+			if low[j] <= v && v <= high[j] {
+				covered[j]++
+			}
+		}
+	}
+	for j, c := range covered {
+		coverage := float64(c) / float64(len(inputs))
+		if math.Abs(coverage-level) > tol {
+			t.Errorf("%v: Empirical coverage for output %v is %v, nominal level is %v", name, j, coverage, level)
+		}
+	}
+}
+
+// OutputPredictor is a multi-output model that can also predict a single output
+type OutputPredictor interface {
+	PredictOutput(input []float64, output int) (float64, error)
+}
+
+// TestMultiOutput tests Predict for models with OutputDim > 1 on random inputs. It
+// checks that outputs of every wrong length are rejected, including a non-nil empty
+// output as in TestPredict, that slices returned with a nil output don't alias each
+// other or the model (mutating one doesn't change later predictions), and, if the
+// model is also an OutputPredictor, that each single output prediction matches the
+// corresponding element of Predict.
+func TestMultiOutput(t *testing.T, p Predictor, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	inputDim := p.InputDim()
+	outputDim := p.OutputDim()
+	if outputDim < 2 {
+		panic("multi-output test needs OutputDim > 1")
+	}
+
+	input := make([]float64, inputDim)
+	for i := range input {
+		input[i] = rnd.NormFloat64()
+	}
+	for l := 0; l <= 2*outputDim; l++ {
+		if l == outputDim {
+			continue
+		}
+		output := make([]float64, l)
+		if !rejects(func() error { _, err := p.Predict(input, output); return err }) {
+			t.Errorf("%v: Predict did not reject an output of length %v, OutputDim is %v", name, l, outputDim)
+		}
+	}
+
+	single, isSingle := p.(OutputPredictor)
+	for i := 0; i < nPredictTests; i++ {
+		for j := range input {
+			input[j] = rnd.NormFloat64()
+		}
+		out1, err := p.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		want := make([]float64, outputDim)
+		copy(want, out1)
+		out2, err := p.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		if &out1[0] == &out2[0] {
+			t.Errorf("%v: Predict with nil output returned the same slice twice", name)
+			return
+		}
+		for j := range out1 {
+			out1[j] = rnd.NormFloat64()
+		}
+		if d := s.tol.diff(want, out2); d != "" {
+			t.Errorf("%v: Modifying one predicted output changed another: %v", name, d)
+			return
+		}
+		out3, err := p.Predict(input, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return
+		}
+		if d := s.tol.diff(want, out3); d != "" {
+			t.Errorf("%v: Modifying a predicted output changed later predictions: %v", name, d)
+			return
+		}
+
+		if !isSingle {
+			continue
+		}
+		for j := 0; j < outputDim; j++ {
+			v, err := single.PredictOutput(input, j)
+			if err != nil {
+				t.Errorf("%v: Error predicting output %v: %v", name, j, err)
+				return
+			}
+			if !s.tol.equal(v, want[j]) {
+				t.Errorf("%v: PredictOutput for output %v doesn't match Predict. PredictOutput: %v, Predict: %v", name, j, v, want[j])
+				return
+			}
+		}
+	}
+	if isSingle {
+		if !rejects(func() error { _, err := single.PredictOutput(input, outputDim); return err }) {
+			t.Errorf("%v: PredictOutput did not reject an output index too large", name)
+		}
+		if !rejects(func() error { _, err := single.PredictOutput(input, -1); return err }) {
+			t.Errorf("%v: PredictOutput did not reject a negative output index", name)
+		}
+	}
+}
+
+// QuantileRegressor is a model that predicts the q-th quantile of each output.
+// As with Predict, a nil output is allocated.
+type QuantileRegressor interface {
+	PredictQuantile(input []float64, q float64, output []float64) ([]float64, error)
+	InputOutputer
+}
+
+// testQuantiles are the quantiles checked by TestQuantileRegressor
+var testQuantiles = []float64{0.1, 0.5, 0.9}
+
+// TestQuantileRegressor tests a trained quantile regressor on held-out samples whose
+// noise has known quantiles. At every input it checks that the predicted 0.1, 0.5 and
+// 0.9 quantiles are ordered, and for each quantile q and output, that the fraction of
+// true outputs at or below the predicted quantile is within tol of q.
+func TestQuantileRegressor(t *testing.T, p QuantileRegressor, inputs, outputs [][]float64, tol float64, name string) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	outputDim := p.OutputDim()
+	below := make([][]int, len(testQuantiles))
+	for k := range below {
+		below[k] = make([]int, outputDim)
+	}
+	preds := make([][]float64, len(testQuantiles))
+	for i := range inputs {
+		for k, q := range testQuantiles {
+			var err error
+			preds[k], err = p.PredictQuantile(inputs[i], q, preds[k])
+			if err != nil {
+				t.Errorf("%v: Error predicting quantile %v for sample %v: %v", name, q, i, err)
+				return
+			}
+			for j, v := range outputs[i] {
+				if v <= preds[k][j] {
+					below[k][j]++
+				}
+			}
+		}
+		for k := 1; k < len(testQuantiles); k++ {
+			for j := 0; j < outputDim; j++ {
+				if preds[k-1][j] > preds[k][j] {
+					t.Errorf("%v: quantiles not monotonic for sample %v output %v: q%v = %v > q%v = %v", name, i, j, testQuantiles[k-1], preds[k-1][j], testQuantiles[k], preds[k][j])
+					return
+				}
+			}
+		}
+	}
+	for k, q := range testQuantiles {
+		for j, c := range below[k] {
+			coverage := float64(c) / float64(len(inputs))
+			if math.Abs(coverage-q) > tol {
+				t.Errorf("%v: Empirical coverage of quantile %v for output %v is %v", name, q, j, coverage)
+			}
+		}
+	}
+}
+
+// TestApproximation tests that a trained single-output model approximates a known
+// target function f, checking that the root mean squared difference between the
+// prediction and f over points is at most tol. points is typically a dense grid over
+// the training domain, such as one from datagen's Function.Grid.
+func TestApproximation(t *testing.T, p Predictor, f func(x []float64) float64, points [][]float64, tol float64, name string) {
+	if p.OutputDim() != 1 {
+		panic("approximation test needs OutputDim == 1")
+	}
+	if len(points) == 0 {
+		panic("at least one point needed")
+	}
+	var sum float64
+	var output []float64
+	for i, x := range points {
+		var err error
+		output, err = p.Predict(x, output)
+		if err != nil {
+			t.Errorf("%v: Error predicting at point %v: %v", name, i, err)
+			return
+		}
+		d := output[0] - f(x)
+		sum += d * d
+	}
+	if rms := math.Sqrt(sum / float64(len(points))); !(rms <= tol) {
+		t.Errorf("%v: RMS error against the true function is %v, more than %v", name, rms, tol)
+	}
+}
@@ -37,6 +37,17 @@ func panics(f func()) (b bool) {
 	return
 }
 
+// rejects returns true if f either panics or returns a non-nil error
+func rejects(f func() error) (b bool) {
+	defer func() {
+		err := recover()
+		if err != nil {
+			b = true
+		}
+	}()
+	return f() != nil
+}
+
 func maybe(f func()) (b bool) {
 	defer func() {
 		err := recover()
@@ -57,78 +68,143 @@ type ParameterGetterSetter interface {
 	SetParameters([]float64)
 }
 
-func TestGetAndSetParameters(t *testing.T, p ParameterGetterSetter, name string) {
+// TestGetAndSetParameters tests the Parameters and SetParameters contract. The
+// comparison of set and returned parameters is exact unless the RoundTripTol or Tol
+// option is given. The checks are run as subtests named NilInput, Copy, SetParameters,
+// BadLengthLong, BadLengthShort and ZeroParameters, so they can be selected with -run.
+func TestGetAndSetParameters(t *testing.T, p ParameterGetterSetter, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	if !t.Run("NilInput", func(t *testing.T) { testParametersNilInput(t, p, name) }) {
+		// The remaining checks rely on Parameters(nil)
+		return
+	}
+	t.Run("Copy", func(t *testing.T) { testParametersCopy(t, p, rnd, name) })
+	t.Run("SetParameters", func(t *testing.T) { testSetParameters(t, p, s, rnd, name) })
+	t.Run("BadLengthLong", func(t *testing.T) { testParametersBadLength(t, p, p.NumParameters()+3, "long", name) })
+	if p.NumParameters() == 0 {
+		t.Run("ZeroParameters", func(t *testing.T) { testZeroParameters(t, p, name) })
+		return
+	}
+	t.Run("BadLengthShort", func(t *testing.T) { testParametersBadLength(t, p, p.NumParameters()-1, "short", name) })
+}
 
-	// Test that we can get parameters from nil
-	// TODO: Add panic guard
+// testParametersNilInput checks that Parameters(nil) allocates a slice of the right
+// length, with the same values as Parameters with a non-nil argument
+func testParametersNilInput(t *testing.T, p ParameterGetterSetter, name string) {
 	var nilParam []float64
 	f := func() {
 		nilParam = p.Parameters(nil)
 	}
-
 	if maybe(f) {
 		t.Errorf("%v: Parameters panicked with nil input", name)
 		return
 	}
-
 	if len(nilParam) != p.NumParameters() {
 		t.Errorf("%v: On nil input, incorrect length returned from Parameters()", name)
+		return
 	}
-	nilParamCopy := make([]float64, p.NumParameters())
-	copy(nilParamCopy, nilParam)
 	nonNilParam := make([]float64, p.NumParameters())
 	p.Parameters(nonNilParam)
 	if !floats.Equal(nilParam, nonNilParam) {
 		t.Errorf("%v: Return from Parameters() with nil argument and non nil argument are different", name)
 	}
-	for i := range nonNilParam {
-		nonNilParam[i] = rand.NormFloat64()
+}
+
+// testParametersCopy checks that modifying the return from Parameters doesn't modify
+// the underlying parameters or the return from another call
+func testParametersCopy(t *testing.T, p ParameterGetterSetter, rnd *rand.Rand, name string) {
+	nilParam := p.Parameters(nil)
+	nilParamCopy := make([]float64, len(nilParam))
+	copy(nilParamCopy, nilParam)
+	nonNilParam := make([]float64, p.NumParameters())
+	p.Parameters(nonNilParam)
+	for i := range nilParam {
+		nilParam[i] = rnd.NormFloat64()
+		nonNilParam[i] = rnd.NormFloat64()
 	}
-	if !floats.Equal(nilParam, nilParamCopy) {
+	if !floats.Equal(p.Parameters(nil), nilParamCopy) {
 		t.Errorf("%v: Modifying the return from Parameters modified the underlying parameters", name)
 	}
+
+	// Successive calls must not return the same cached buffer
+	first := p.Parameters(nil)
+	second := p.Parameters(nil)
+	for i := range first {
+		first[i] = rnd.NormFloat64()
+	}
+	if !floats.Equal(second, nilParamCopy) {
+		t.Errorf("%v: Successive calls to Parameters(nil) return slices sharing a backing array", name)
+	}
+}
+
+// testSetParameters checks that SetParameters doesn't modify or retain its input, and
+// that the parameters round trip
+func testSetParameters(t *testing.T, p ParameterGetterSetter, s *settings, rnd *rand.Rand, name string) {
+	newParam := make([]float64, p.NumParameters())
+	for i := range newParam {
+		newParam[i] = rnd.NormFloat64()
+	}
 	setParam := make([]float64, p.NumParameters())
-	copy(setParam, nonNilParam)
+	copy(setParam, newParam)
 	p.SetParameters(setParam)
-	if !floats.Equal(setParam, nonNilParam) {
+	if !floats.Equal(setParam, newParam) {
 		t.Errorf("%v: Input slice modified during call to SetParameters", name)
 	}
 
 	afterParam := p.Parameters(nil)
-	if !floats.Equal(afterParam, setParam) {
-		t.Errorf("%v: Set parameters followed by Parameters don't return the same argument", name)
+	if d := s.roundTrip.diff(setParam, afterParam); d != "" {
+		t.Errorf("%v: Set parameters followed by Parameters don't return the same argument: %v", name, d)
 	}
+	for i := range setParam {
+		setParam[i] = rnd.NormFloat64()
+	}
+	if !floats.Equal(p.Parameters(nil), afterParam) {
+		t.Errorf("%v: Modifying the input to SetParameters after the call modified the underlying parameters", name)
+	}
+}
 
-	// Test that there are panics on bad length arguments
-	badLength := make([]float64, p.NumParameters()+3)
-
-	f = func() {
+// testParametersBadLength checks that Parameters and SetParameters panic given a
+// slice of length l
+func testParametersBadLength(t *testing.T, p ParameterGetterSetter, l int, kind, name string) {
+	badLength := make([]float64, l)
+	f := func() {
 		p.Parameters(badLength)
 	}
 	if !panics(f) {
-		t.Errorf("%v: Parameters did not panic given a slice too long", name)
+		t.Errorf("%v: Parameters did not panic given a slice too %v", name, kind)
 	}
 	f = func() {
 		p.SetParameters(badLength)
 	}
 	if !panics(f) {
-		t.Errorf("%v: SetParameters did not panic given a slice too long", name)
+		t.Errorf("%v: SetParameters did not panic given a slice too %v", name, kind)
 	}
-	if p.NumParameters() == 0 {
+}
+
+// testZeroParameters checks the parameter contract for models without parameters.
+// Parameters(nil) must return an empty, non-nil slice, setting nil or empty
+// parameters must succeed, and setting non-empty parameters must panic.
+func testZeroParameters(t *testing.T, p ParameterGetterSetter, name string) {
+	var param []float64
+	if maybe(func() { param = p.Parameters(nil) }) {
+		t.Errorf("%v: Parameters panicked with nil input and no parameters", name)
 		return
 	}
-	badLength = badLength[:p.NumParameters()-1]
-	f = func() {
-		p.Parameters(badLength)
+	if param == nil || len(param) != 0 {
+		t.Errorf("%v: With no parameters, Parameters(nil) did not return an empty non-nil slice", name)
 	}
-	if !panics(f) {
-		t.Errorf("%v: Parameters did not panic given a slice too short", name)
+	if panics(func() { p.SetParameters(nil) }) {
+		t.Errorf("%v: With no parameters, SetParameters(nil) panicked", name)
 	}
-	f = func() {
-		p.SetParameters(badLength)
+	if panics(func() { p.SetParameters([]float64{}) }) {
+		t.Errorf("%v: With no parameters, SetParameters with an empty slice panicked", name)
 	}
-	if !panics(f) {
-		t.Errorf("%v: SetParameters did not panic given a slice too short", name)
+	if !panics(func() { p.SetParameters([]float64{0}) }) {
+		t.Errorf("%v: With no parameters, SetParameters did not panic with a non-empty slice", name)
+	}
+	if !panics(func() { p.Parameters([]float64{0}) }) {
+		t.Errorf("%v: With no parameters, Parameters did not panic with a non-empty slice", name)
 	}
 }
 
@@ -150,13 +226,21 @@ func TestInputOutputDim(t *testing.T, io InputOutputer, trueInputDim, trueOutput
 
 type Predictor interface {
 	Predict(input, output []float64) ([]float64, error)
-	PredictBatch(inputs common.RowMatrix, outputs common.MutableRowMatrix) (common.MutableRowMatrix, error)
 	InputOutputer
 }
 
-// TestPredict tests that predict returns the expected value, and that calling predict in parallel
-// also works
-func TestPredictAndBatch(t *testing.T, p Predictor, inputs, trueOutputs common.RowMatrix, name string) {
+type BatchPredictor interface {
+	Predictor
+	PredictBatch(inputs common.RowMatrix, outputs common.MutableRowMatrix) (common.MutableRowMatrix, error)
+}
+
+// TestPredictAndBatch tests that predict returns the expected value, and that calling predict in parallel
+// also works. Outputs are compared using the Tol option, by default exactly, except against trueOutputs,
+// which by default are matched to within 1e-14.
+func TestPredictAndBatch(t *testing.T, p BatchPredictor, inputs, trueOutputs common.RowMatrix, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	trueTol := s.deterministicTol(1e-14)
 	nSamples, inputDim := inputs.Dims()
 	if inputDim != p.InputDim() {
 		panic("input Dim doesn't match predictor input dim")
@@ -194,7 +278,7 @@ func TestPredictAndBatch(t *testing.T, p Predictor, inputs, trueOutputs common.R
 		}
 		out2 := make([]float64, outputDim)
 		for j := 0; j < outputDim; j++ {
-			out2[j] = rand.NormFloat64()
+			out2[j] = rnd.NormFloat64()
 		}
 
 		_, err = p.Predict(input, out2)
@@ -207,12 +291,12 @@ func TestPredictAndBatch(t *testing.T, p Predictor, inputs, trueOutputs common.R
 			break
 		}
 
-		if !floats.Equal(out1, out2) {
-			t.Errorf(name + ": different answers with nil and non-nil predict ")
+		if d := s.tol.diff(out1, out2); d != "" {
+			t.Errorf("%v: different answers with nil and non-nil predict for row %v: %v", name, i, d)
 			break
 		}
-		if !floats.EqualApprox(out1, trueOut, 1e-14) {
-			t.Errorf("%v: predicted output doesn't match for row %v. Expected %v, found %v", name, i, trueOut, out1)
+		if d := trueTol.diff(trueOut, out1); d != "" {
+			t.Errorf("%v: predicted output doesn't match for row %v: %v", name, i, d)
 			break
 		}
 	}
@@ -272,10 +356,7 @@ func TestPredictAndBatch(t *testing.T, p Predictor, inputs, trueOutputs common.R
 	outputs := mat64.NewDense(nSamples, outputDim, nil)
 	_, err = p.PredictBatch(inputs, outputs)
 
-	pd := predOutput.(*mat64.Dense)
-	if !pd.Equals(outputs) {
-		t.Errorf("Different outputs from predict batch with nil and non-nil")
-	}
+	CheckMatricesEqual(t, predOutput, outputs, s.tol, name+": PredictBatch with non-nil output against nil output")
 
 	badInputs := mat64.NewDense(nSamples, inputDim+1, nil)
 	_, err = p.PredictBatch(badInputs, outputs)
@@ -300,9 +381,12 @@ type DerivTester interface {
 }
 
 // TestDeriv uses finite difference to test that the prediction from Deriv
-// is correct, and tests that computing the loss in parallel works properly
-// Only does finite difference for the first nTest to save time
-func TestDeriv(t *testing.T, trainable DerivTester, inputs, trueOutputs common.RowMatrix, name string) {
+// is correct, and tests that computing the loss in parallel works properly.
+// The perturbed parameters of each batch are evaluated concurrently. The step
+// size and tolerance can be set with the FDStep and FDTol options. On failure
+// the error at a sweep of step sizes is reported.
+func TestDeriv(t *testing.T, trainable DerivTester, inputs, trueOutputs common.RowMatrix, name string, opts ...Option) {
+	s := newSettings(opts)
 
 	// Set the parameters to something random
 	trainable.RandomizeParameters()
@@ -313,32 +397,32 @@ func TestDeriv(t *testing.T, trainable DerivTester, inputs, trueOutputs common.R
 
 	batchGrad := train.NewBatchGradBased(trainable, true, inputs, trueOutputs, losser, regularizer)
 
-	derivative := make([]float64, trainable.NumParameters())
+	numParameters := trainable.NumParameters()
+	derivative := make([]float64, numParameters)
 	parameters := trainable.Parameters(nil)
 	// Don't need to check loss, because if predict is right and losser is right then loss must be correct
 	_ = batchGrad.ObjGrad(parameters, derivative)
 
-	fdDerivative := make([]float64, trainable.NumParameters())
-
-	wg := &sync.WaitGroup{}
-	wg.Add(trainable.NumParameters())
-	for i := 0; i < trainable.NumParameters(); i++ {
-		go func(i int) {
-			newParameters := make([]float64, trainable.NumParameters())
-			tmpDerivative := make([]float64, trainable.NumParameters())
-			copy(newParameters, parameters)
-			newParameters[i] += fdStep
-			loss1 := batchGrad.ObjGrad(newParameters, tmpDerivative)
-			newParameters[i] -= 2 * fdStep
-			loss2 := batchGrad.ObjGrad(newParameters, tmpDerivative)
-			newParameters[i] += fdStep
-			fdDerivative[i] = (loss1 - loss2) / (2 * fdStep)
-			wg.Done()
-		}(i)
-	}
-	wg.Wait()
-	if !floats.EqualApprox(derivative, fdDerivative, 1e-6) {
-		t.Errorf("%v: deriv doesn't match: Finite Difference: %v, Analytic: %v", name, fdDerivative, derivative)
+	objective := batched(func(points *mat64.Dense) (mat64.Matrix, error) {
+		r, _ := points.Dims()
+		values := make([]float64, r)
+		wg := &sync.WaitGroup{}
+		wg.Add(r)
+		for i := 0; i < r; i++ {
+			go func(i int) {
+				tmpDerivative := make([]float64, numParameters)
+				values[i] = batchGrad.ObjGrad(points.Row(nil, i), tmpDerivative)
+				wg.Done()
+			}(i)
+		}
+		wg.Wait()
+		return mat64.NewDense(r, 1, values), nil
+	})
+	// The values of an objective always have the right size
+	fd, _ := fdJacobianSteps(objective, parameters, 1, fdSteps(parameters, s.fdStep, false))
+	fdDerivative := fd.Row(nil, 0)
+	cmp := Tolerance{Abs: s.fdTol, Rel: s.fdTol}
+	if d := cmp.diff(fdDerivative, derivative); d != "" {
+		t.Errorf("%v: deriv doesn't match finite difference: %v%v", name, d, gradientSweep(objective, parameters, false, derivative))
 	}
-
 }
@@ -0,0 +1,66 @@
+package regtest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+)
+
+// Regularizer is a penalty on the parameters of a model. LossDeriv returns the
+// penalty and stores its gradient with respect to the parameters in derivative.
+type Regularizer interface {
+	Loss(parameters []float64) float64
+	LossDeriv(parameters, derivative []float64) float64
+}
+
+// TestRegularizer tests a regularizer on parameter vectors of several lengths. It
+// checks that the penalty and its gradient are zero at the zero vector, that the
+// penalty is nonnegative, that Loss and LossDeriv agree, and that the gradient
+// matches finite difference at random parameter vectors. The finite difference step
+// and tolerance can be set with the FDStep and FDTol options. On failure the error at
+// a sweep of step sizes is reported.
+func TestRegularizer(t *testing.T, r Regularizer, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	cmp := Tolerance{Abs: s.fdTol, Rel: s.fdTol}
+	for dim := 1; dim <= maxLossDim; dim++ {
+		parameters := make([]float64, dim)
+		derivative := make([]float64, dim)
+		fdDerivative := make([]float64, dim)
+		for i := range derivative {
+			derivative[i] = math.NaN()
+		}
+
+		loss := r.LossDeriv(parameters, derivative)
+		if loss != 0 {
+			t.Errorf("%v: nonzero penalty at the zero parameter vector for dim %v. Found %v", name, dim, loss)
+			return
+		}
+		if floats.Norm(derivative, math.Inf(1)) != 0 {
+			t.Errorf("%v: nonzero gradient at the zero parameter vector for dim %v. Found %v", name, dim, derivative)
+			return
+		}
+
+		for i := 0; i < nLossTests; i++ {
+			for j := range parameters {
+				parameters[j] = rnd.NormFloat64()
+			}
+			loss := r.Loss(parameters)
+			if loss < 0 || math.IsNaN(loss) {
+				t.Errorf("%v: penalty is negative or NaN for dim %v. Found %v", name, dim, loss)
+				return
+			}
+			lossDeriv := r.LossDeriv(parameters, derivative)
+			if math.Abs(loss-lossDeriv) > lossEqualTol*math.Max(1, math.Abs(loss)) {
+				t.Errorf("%v: Loss and LossDeriv return different penalties for dim %v. Loss: %v, LossDeriv: %v", name, dim, loss, lossDeriv)
+				return
+			}
+			fdGradient(r.Loss, parameters, fdDerivative, s.fdStep)
+			if d := cmp.diff(fdDerivative, derivative); d != "" {
+				t.Errorf("%v: deriv doesn't match finite difference for dim %v: %v%v", name, dim, d, gradientSweep(scalar(r.Loss), parameters, false, derivative))
+				return
+			}
+		}
+	}
+}
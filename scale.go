@@ -0,0 +1,107 @@
+package regtest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/gonum/matrix/mat64"
+)
+
+const scaleRoundTripTol = 1e-12
+
+// Scaler transforms data in place. SetScale sets the transformation from a
+// data set where each row is a sample.
+type Scaler interface {
+	Scale(x []float64) error
+	Unscale(x []float64) error
+	SetScale(data *mat64.Dense) error
+}
+
+// ScaleChecker checks that scaled data has the statistics documented by a Scaler.
+// Each row of scaled is a sample.
+type ScaleChecker func(scaled *mat64.Dense) error
+
+// ZeroMeanUnitVariance returns a ScaleChecker verifying that every column of the
+// scaled data has mean zero and (population) standard deviation one to within tol.
+// Constant columns are required to have zero mean only.
+func ZeroMeanUnitVariance(tol float64) ScaleChecker {
+	return func(scaled *mat64.Dense) error {
+		r, c := scaled.Dims()
+		col := make([]float64, r)
+		for j := 0; j < c; j++ {
+			scaled.Col(col, j)
+			mean := floats.Sum(col) / float64(r)
+			var variance float64
+			for _, v := range col {
+				variance += (v - mean) * (v - mean)
+			}
+			std := math.Sqrt(variance / float64(r))
+			if math.Abs(mean) > tol {
+				return fmt.Errorf("column %v has mean %v", j, mean)
+			}
+			if std != 0 && math.Abs(std-1) > tol {
+				return fmt.Errorf("column %v has standard deviation %v", j, std)
+			}
+		}
+		return nil
+	}
+}
+
+// TestScaler tests a Scaler on data, where each row is a sample. It checks that
+// SetScale does not modify data, that Unscale(Scale(x)) recovers x, that inputs of
+// the wrong length are rejected, and, if check is not nil, that the scaled data
+// passes check. The round trip must match to within scaleRoundTripTol unless the Tol
+// option is given.
+func TestScaler(t *testing.T, s Scaler, data *mat64.Dense, check ScaleChecker, name string, opts ...Option) {
+	cmp := newSettings(opts).deterministicTol(scaleRoundTripTol)
+	dataCpy := &mat64.Dense{}
+	dataCpy.Clone(data)
+	err := s.SetScale(data)
+	if err != nil {
+		t.Errorf("%v: Error setting scale: %v", name, err)
+		return
+	}
+	if !dataCpy.Equals(data) {
+		t.Errorf("%v: data modified during call to SetScale", name)
+	}
+
+	nSamples, dim := dataCpy.Dims()
+	scaled := mat64.NewDense(nSamples, dim, nil)
+	x := make([]float64, dim)
+	orig := make([]float64, dim)
+	for i := 0; i < nSamples; i++ {
+		dataCpy.Row(orig, i)
+		copy(x, orig)
+		err := s.Scale(x)
+		if err != nil {
+			t.Errorf("%v: Error scaling row %v: %v", name, i, err)
+			return
+		}
+		scaled.SetRow(i, x)
+		err = s.Unscale(x)
+		if err != nil {
+			t.Errorf("%v: Error unscaling row %v: %v", name, i, err)
+			return
+		}
+		if d := cmp.diff(orig, x); d != "" {
+			t.Errorf("%v: Unscale(Scale(x)) != x for row %v: %v", name, i, d)
+			return
+		}
+	}
+
+	bad := make([]float64, dim+1)
+	if !rejects(func() error { return s.Scale(bad) }) {
+		t.Errorf("%v: Scale did not reject an input too long", name)
+	}
+	if !rejects(func() error { return s.Unscale(bad) }) {
+		t.Errorf("%v: Unscale did not reject an input too long", name)
+	}
+
+	if check != nil {
+		if err := check(scaled); err != nil {
+			t.Errorf("%v: scaled data has the wrong statistics: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package regtest
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+)
+
+// TestSerializeParameters tests that the parameters of p survive serialization. For each
+// of encoding.BinaryMarshaler, gob.GobEncoder and json.Marshaler that p implements, it
+// marshals p, unmarshals into a value returned by newModel, and checks that
+// NumParameters and Parameters are identical, or match to within the RoundTripTol or
+// Tol option if one is given. newModel must return a fresh (pointer)
+// value of the same type as p.
+func TestSerializeParameters(t *testing.T, p ParameterGetterSetter, newModel func() ParameterGetterSetter, name string, opts ...Option) {
+	s := newSettings(opts)
+	tested := false
+	check := func(format string, fresh ParameterGetterSetter) {
+		tested = true
+		if fresh.NumParameters() != p.NumParameters() {
+			t.Errorf("%v: NumParameters after %v round trip is %v, expected %v", name, format, fresh.NumParameters(), p.NumParameters())
+			return
+		}
+		if d := s.roundTrip.diff(p.Parameters(nil), fresh.Parameters(nil)); d != "" {
+			t.Errorf("%v: Parameters changed during %v round trip: %v", name, format, d)
+		}
+	}
+
+	if m, ok := p.(encoding.BinaryMarshaler); ok {
+		fresh := newModel()
+		u, ok := fresh.(encoding.BinaryUnmarshaler)
+		if !ok {
+			t.Errorf("%v: model is a BinaryMarshaler but not a BinaryUnmarshaler", name)
+			return
+		}
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Errorf("%v: Error marshaling binary: %v", name, err)
+			return
+		}
+		if err := u.UnmarshalBinary(data); err != nil {
+			t.Errorf("%v: Error unmarshaling binary: %v", name, err)
+			return
+		}
+		check("binary", fresh)
+	}
+
+	if _, ok := p.(gob.GobEncoder); ok {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(p); err != nil {
+			t.Errorf("%v: Error gob encoding: %v", name, err)
+			return
+		}
+		fresh := newModel()
+		if err := gob.NewDecoder(&buf).Decode(fresh); err != nil {
+			t.Errorf("%v: Error gob decoding: %v", name, err)
+			return
+		}
+		check("gob", fresh)
+	}
+
+	if _, ok := p.(json.Marshaler); ok {
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Errorf("%v: Error marshaling JSON: %v", name, err)
+			return
+		}
+		fresh := newModel()
+		if err := json.Unmarshal(data, fresh); err != nil {
+			t.Errorf("%v: Error unmarshaling JSON: %v", name, err)
+			return
+		}
+		check("JSON", fresh)
+	}
+
+	if !tested {
+		t.Errorf("%v: model implements none of BinaryMarshaler, GobEncoder or json.Marshaler", name)
+	}
+}
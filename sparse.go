@@ -0,0 +1,77 @@
+package regtest
+
+import (
+	"testing"
+)
+
+const sparseDensity = 0.3
+
+// SparsePredictor is a model that can predict from a sparse input, given as the
+// indices and values of the nonzero entries.
+type SparsePredictor interface {
+	Predictor
+	PredictSparse(indices []int, values []float64, output []float64) ([]float64, error)
+}
+
+// TestSparsePredict tests that predictions from random sparse inputs match, to within
+// tol, predictions on their dense expansions, and that PredictSparse rejects bad
+// arguments (by error or panic) exactly when Predict does, as well as out of range
+// indices and mismatched indices and values. A tolerance set with the Tol option
+// overrides tol.
+func TestSparsePredict(t *testing.T, p SparsePredictor, tol float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	rnd := s.rand(t)
+	cmp := s.deterministicTol(tol)
+	inputDim := p.InputDim()
+	outputDim := p.OutputDim()
+
+	dense := make([]float64, inputDim)
+	var indices []int
+	var values []float64
+	for i := 0; i < nPredictTests; i++ {
+		indices = indices[:0]
+		values = values[:0]
+		for j := range dense {
+			dense[j] = 0
+			if rnd.Float64() < sparseDensity {
+				dense[j] = rnd.NormFloat64()
+				indices = append(indices, j)
+				values = append(values, dense[j])
+			}
+		}
+		want, err := p.Predict(dense, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting dense input: %v", name, err)
+			return
+		}
+		got, err := p.PredictSparse(indices, values, nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting sparse input: %v", name, err)
+			return
+		}
+		if d := cmp.diff(want, got); d != "" {
+			t.Errorf("%v: sparse and dense predictions differ: %v", name, d)
+			return
+		}
+	}
+
+	for _, l := range []int{outputDim - 1, outputDim + 1} {
+		if l < 0 {
+			continue
+		}
+		denseRejects := rejects(func() error { _, err := p.Predict(dense, make([]float64, l)); return err })
+		sparseRejects := rejects(func() error { _, err := p.PredictSparse(indices, values, make([]float64, l)); return err })
+		if denseRejects != sparseRejects {
+			t.Errorf("%v: Predict and PredictSparse disagree on rejecting an output of length %v. Predict: %v, PredictSparse: %v", name, l, denseRejects, sparseRejects)
+		}
+	}
+	if !rejects(func() error { _, err := p.PredictSparse([]int{inputDim}, []float64{1}, nil); return err }) {
+		t.Errorf("%v: PredictSparse did not reject an index too large", name)
+	}
+	if !rejects(func() error { _, err := p.PredictSparse([]int{-1}, []float64{1}, nil); return err }) {
+		t.Errorf("%v: PredictSparse did not reject a negative index", name)
+	}
+	if !rejects(func() error { _, err := p.PredictSparse([]int{0}, []float64{1, 2}, nil); return err }) {
+		t.Errorf("%v: PredictSparse did not reject indices and values of different lengths", name)
+	}
+}
@@ -0,0 +1,241 @@
+package regtest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/gonum/floats"
+	"github.com/gonum/matrix/mat64"
+)
+
+// Tolerance is a combined absolute, relative and units-in-the-last-place tolerance.
+// Two values match if they are equal to within Abs, to within Rel relative to the
+// larger magnitude, or at most ULP representable floats apart. The zero Tolerance
+// requires exact equality. A ULP tolerance alone is useful for checking that a
+// refactored implementation is bit-for-bit, or within a few ULP, compatible with the
+// previous one.
+//
+// By default NaN matches nothing, as with ==. If NaNEqual is true NaN matches NaN, for
+// round trip and golden tests of models that store NaN sentinels. If SignedZero is
+// true +0 and -0 do not match.
+type Tolerance struct {
+	Abs, Rel float64
+	ULP      uint64
+
+	NaNEqual   bool
+	SignedZero bool
+}
+
+// equal returns whether a and b match to within tol
+func (tol Tolerance) equal(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return tol.NaNEqual && math.IsNaN(a) && math.IsNaN(b)
+	}
+	if tol.SignedZero && a == 0 && b == 0 {
+		return math.Signbit(a) == math.Signbit(b)
+	}
+	if floats.EqualWithinAbsOrRel(a, b, tol.Abs, tol.Rel) {
+		return true
+	}
+	return tol.ULP > 0 && ulpDistance(a, b) <= tol.ULP
+}
+
+// ulpDistance returns the number of representable floats between a and b, counting
+// +0 and -0 as adjacent. It is the maximum uint64 if either is NaN.
+func ulpDistance(a, b float64) uint64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.MaxUint64
+	}
+	ia, ib := ordered(a), ordered(b)
+	if ia > ib {
+		return ia - ib
+	}
+	return ib - ia
+}
+
+// ordered maps the bits of x to an unsigned integer in the same order as the floats
+func ordered(x float64) uint64 {
+	b := math.Float64bits(x)
+	if b>>63 == 1 {
+		return ^b
+	}
+	return b | 1<<63
+}
+
+// equalSlices returns whether a and b have the same length and every pair of elements
+// matches to within tol
+func (tol Tolerance) equalSlices(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !tol.equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalPredictions returns whether two sets of predictions match to within tol
+func (tol Tolerance) equalPredictions(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !tol.equalSlices(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxDiffs is the maximum number of offending elements listed in a failure message
+const maxDiffs = 5
+
+// mismatch is an element that does not match. excess is its absolute error as a
+// multiple of the error allowed by the tolerance.
+type mismatch struct {
+	label     string
+	want, got float64
+	abs, rel  float64
+	excess    float64
+}
+
+// diff describes how got differs from want under tol: the number of mismatched
+// elements and the index, expected and actual values, and absolute and relative
+// errors of the worst few. It returns the empty string if they match.
+func (tol Tolerance) diff(want, got []float64) string {
+	if len(want) != len(got) {
+		return fmt.Sprintf("length %v, expected %v", len(got), len(want))
+	}
+	var bad []mismatch
+	for i := range want {
+		if !tol.equal(want[i], got[i]) {
+			bad = append(bad, tol.newMismatch(fmt.Sprintf("[%v]", i), want[i], got[i]))
+		}
+	}
+	return describe(bad, len(want))
+}
+
+// diffRows is diff for sets of rows such as predictions, where element j of row i is
+// labelled [i][j]
+func (tol Tolerance) diffRows(want, got [][]float64) string {
+	if len(want) != len(got) {
+		return fmt.Sprintf("%v rows, expected %v", len(got), len(want))
+	}
+	var bad []mismatch
+	n := 0
+	for i := range want {
+		if len(want[i]) != len(got[i]) {
+			return fmt.Sprintf("row %v has length %v, expected %v", i, len(got[i]), len(want[i]))
+		}
+		for j := range want[i] {
+			if !tol.equal(want[i][j], got[i][j]) {
+				bad = append(bad, tol.newMismatch(fmt.Sprintf("[%v][%v]", i, j), want[i][j], got[i][j]))
+			}
+		}
+		n += len(want[i])
+	}
+	return describe(bad, n)
+}
+
+func (tol Tolerance) newMismatch(label string, want, got float64) mismatch {
+	abs := math.Abs(got - want)
+	if math.IsNaN(abs) {
+		abs = math.Inf(1)
+	}
+	larger := math.Max(math.Abs(want), math.Abs(got))
+	excess := abs / math.Max(tol.Abs, tol.Rel*larger)
+	if math.IsNaN(excess) {
+		excess = math.Inf(1)
+	}
+	return mismatch{
+		label:  label,
+		want:   want,
+		got:    got,
+		abs:    abs,
+		rel:    abs / larger,
+		excess: excess,
+	}
+}
+
+// describe formats the worst of the mismatches out of n elements, largest error
+// relative to the tolerance first, and largest absolute error among equal excesses
+func describe(bad []mismatch, n int) string {
+	if len(bad) == 0 {
+		return ""
+	}
+	sort.SliceStable(bad, func(i, j int) bool {
+		if bad[i].excess != bad[j].excess {
+			return bad[i].excess > bad[j].excess
+		}
+		return bad[i].abs > bad[j].abs
+	})
+	return fmt.Sprintf("%v of %v elements differ; ", len(bad), n) + listed(len(bad), func(k int) string {
+		m := bad[k]
+		return fmt.Sprintf("%v expected %v, got %v (abs err %.3g, rel err %.3g)", m.label, m.want, m.got, m.abs, m.rel)
+	})
+}
+
+// listed formats the first maxDiffs of n offending elements with item, separated by
+// semicolons and followed by an ellipsis if any are left out
+func listed(n int, item func(k int) string) string {
+	var msg string
+	for k := 0; k < n; k++ {
+		if k == maxDiffs {
+			msg += "; ..."
+			break
+		}
+		if k > 0 {
+			msg += "; "
+		}
+		msg += item(k)
+	}
+	return msg
+}
+
+// CheckMatricesEqual fails if got does not have the same shape as want, or if any of
+// their elements do not match to within tol, reporting the row and column of the
+// worst mismatches, and returns whether they match. It is intended for Gram matrices,
+// Jacobians and batch predictions.
+func CheckMatricesEqual(t *testing.T, want, got mat64.Matrix, tol Tolerance, name string) bool {
+	wr, wc := want.Dims()
+	gr, gc := got.Dims()
+	if wr != gr || wc != gc {
+		t.Errorf("%v: matrix is %v×%v, expected %v×%v", name, gr, gc, wr, wc)
+		return false
+	}
+	if d := tol.diffRows(rows(want), rows(got)); d != "" {
+		t.Errorf("%v: matrices differ: %v", name, d)
+		return false
+	}
+	return true
+}
+
+// ConditionNumber returns the 2-norm condition number of m, the ratio of its largest
+// to smallest singular value, computed by singular value decomposition.
+func ConditionNumber(m mat64.Matrix) float64 {
+	var a mat64.Dense
+	a.Clone(m)
+	return mat64.SVD(&a, math.Pow(2, -52), math.Pow(2, -966), false, false).Cond()
+}
+
+// ConditionTol returns tol with Abs and Rel multiplied by the condition number of the
+// design matrix, for comparing a trained model against a closed-form linear algebra
+// solution such as least squares coefficients. The error of a stable solver grows
+// with the condition number, so a fixed tolerance is either loose on well-conditioned
+// problems or flaky on ill-conditioned ones. ULP is unchanged. If the design is
+// singular to working precision, with a condition number of at least 2^52, scaling
+// by it would make every comparison pass (or fail, as 0·Inf is NaN), so tol is
+// returned unchanged with an error for the caller to report.
+func ConditionTol(design mat64.Matrix, tol Tolerance) (Tolerance, error) {
+	cond := ConditionNumber(design)
+	if math.IsNaN(cond) || cond >= math.Pow(2, 52) {
+		return tol, fmt.Errorf("design is singular: condition number %v", cond)
+	}
+	tol.Abs *= cond
+	tol.Rel *= cond
+	return tol, nil
+}
@@ -0,0 +1,218 @@
+package regtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestToleranceEqual(t *testing.T) {
+	for _, test := range []struct {
+		tol   Tolerance
+		a, b  float64
+		equal bool
+	}{
+		{tol: Tolerance{}, a: 1, b: 1, equal: true},
+		{tol: Tolerance{}, a: 1, b: math.Nextafter(1, 2), equal: false},
+		{tol: Tolerance{}, a: 0, b: math.Copysign(0, -1), equal: true},
+		{tol: Tolerance{}, a: math.Inf(1), b: math.Inf(1), equal: true},
+		{tol: Tolerance{}, a: math.Inf(1), b: math.Inf(-1), equal: false},
+		{tol: Tolerance{Abs: 1e-3}, a: 1, b: 1.0005, equal: true},
+		{tol: Tolerance{Abs: 1e-3}, a: 1, b: 1.002, equal: false},
+		{tol: Tolerance{Abs: 1e-3}, a: 1e6, b: 1e6 + 1, equal: false},
+		{tol: Tolerance{Rel: 1e-3}, a: 1e6, b: 1e6 + 1, equal: true},
+		{tol: Tolerance{Rel: 1e-3}, a: 1e-6, b: 2e-6, equal: false},
+		{tol: Tolerance{Abs: 1e-3, Rel: 1e-3}, a: 1e-6, b: 2e-6, equal: true},
+		{tol: Tolerance{Abs: 1e-3, Rel: 1e-3}, a: 1e6, b: 1e6 + 1, equal: true},
+		{tol: Tolerance{Abs: 1, Rel: 1}, a: math.NaN(), b: math.NaN(), equal: false},
+		{tol: Tolerance{Abs: 1, Rel: 1}, a: math.NaN(), b: 0, equal: false},
+	} {
+		if got := test.tol.equal(test.a, test.b); got != test.equal {
+			t.Errorf("%+v.equal(%v, %v) = %v, expected %v", test.tol, test.a, test.b, got, test.equal)
+		}
+		if got := test.tol.equal(test.b, test.a); got != test.equal {
+			t.Errorf("%+v.equal(%v, %v) = %v, expected %v", test.tol, test.b, test.a, got, test.equal)
+		}
+	}
+}
+
+func TestToleranceEqualSlices(t *testing.T) {
+	tol := Tolerance{Abs: 0.1}
+	for _, test := range []struct {
+		a, b  []float64
+		equal bool
+	}{
+		{a: nil, b: nil, equal: true},
+		{a: nil, b: []float64{}, equal: true},
+		{a: []float64{1, 2}, b: []float64{1.05, 1.95}, equal: true},
+		{a: []float64{1, 2}, b: []float64{1, 2.2}, equal: false},
+		{a: []float64{1, 2}, b: []float64{1}, equal: false},
+	} {
+		if got := tol.equalSlices(test.a, test.b); got != test.equal {
+			t.Errorf("equalSlices(%v, %v) = %v, expected %v", test.a, test.b, got, test.equal)
+		}
+	}
+}
+
+func TestDeterministicTol(t *testing.T) {
+	if got := newSettings(nil).deterministicTol(1e-3); got != (Tolerance{Abs: 1e-3, Rel: 1e-3}) {
+		t.Errorf("default is %+v", got)
+	}
+	set := Tolerance{ULP: 2}
+	if got := newSettings([]Option{Tol(set)}).deterministicTol(1e-3); got != set {
+		t.Errorf("Tol(%+v) gave %+v", set, got)
+	}
+	if got := newSettings([]Option{Tol(set)}).roundTrip; got != set {
+		t.Errorf("Tol(%+v) gave a round trip tolerance of %+v", set, got)
+	}
+}
+
+func TestULPDistance(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	for _, test := range []struct {
+		a, b float64
+		ulp  uint64
+	}{
+		{a: 1, b: 1, ulp: 0},
+		{a: 1, b: math.Nextafter(1, 2), ulp: 1},
+		{a: 1, b: math.Nextafter(math.Nextafter(1, 0), 0), ulp: 2},
+		{a: 0, b: negZero, ulp: 1},
+		{a: math.SmallestNonzeroFloat64, b: -math.SmallestNonzeroFloat64, ulp: 3},
+		{a: math.MaxFloat64, b: math.Inf(1), ulp: 1},
+		{a: math.NaN(), b: 1, ulp: math.MaxUint64},
+		{a: math.NaN(), b: math.NaN(), ulp: math.MaxUint64},
+	} {
+		if got := ulpDistance(test.a, test.b); got != test.ulp {
+			t.Errorf("ulpDistance(%v, %v) = %v, expected %v", test.a, test.b, got, test.ulp)
+		}
+		if got := ulpDistance(test.b, test.a); got != test.ulp {
+			t.Errorf("ulpDistance(%v, %v) = %v, expected %v", test.b, test.a, got, test.ulp)
+		}
+	}
+}
+
+func TestToleranceULP(t *testing.T) {
+	next := func(x float64, n int) float64 {
+		for i := 0; i < n; i++ {
+			x = math.Nextafter(x, math.Inf(1))
+		}
+		return x
+	}
+	for _, test := range []struct {
+		tol   Tolerance
+		a, b  float64
+		equal bool
+	}{
+		{tol: Tolerance{ULP: 4}, a: 1, b: next(1, 4), equal: true},
+		{tol: Tolerance{ULP: 4}, a: 1, b: next(1, 5), equal: false},
+		{tol: Tolerance{ULP: 4}, a: 1e300, b: next(1e300, 3), equal: true},
+		{tol: Tolerance{ULP: 1}, a: 0, b: math.Copysign(0, -1), equal: true},
+		{tol: Tolerance{ULP: 1}, a: math.NaN(), b: math.NaN(), equal: false},
+		{tol: Tolerance{Abs: 1, ULP: 1}, a: 1, b: 1.5, equal: true},
+	} {
+		if got := test.tol.equal(test.a, test.b); got != test.equal {
+			t.Errorf("%+v.equal(%v, %v) = %v, expected %v", test.tol, test.a, test.b, got, test.equal)
+		}
+	}
+}
+
+func TestToleranceNaNAndSignedZero(t *testing.T) {
+	nan := math.NaN()
+	negZero := math.Copysign(0, -1)
+	for _, test := range []struct {
+		tol   Tolerance
+		a, b  float64
+		equal bool
+	}{
+		{tol: Tolerance{NaNEqual: true}, a: nan, b: nan, equal: true},
+		{tol: Tolerance{NaNEqual: true}, a: nan, b: 0, equal: false},
+		{tol: Tolerance{NaNEqual: true}, a: nan, b: math.Inf(1), equal: false},
+		{tol: Tolerance{NaNEqual: true, Abs: math.Inf(1)}, a: nan, b: 1, equal: false},
+		{tol: Tolerance{SignedZero: true}, a: 0, b: negZero, equal: false},
+		{tol: Tolerance{SignedZero: true}, a: negZero, b: negZero, equal: true},
+		{tol: Tolerance{SignedZero: true, Abs: 1}, a: 0, b: negZero, equal: false},
+		{tol: Tolerance{SignedZero: true, Abs: 1}, a: 0, b: -1e-300, equal: true},
+	} {
+		if got := test.tol.equal(test.a, test.b); got != test.equal {
+			t.Errorf("%+v.equal(%v, %v) = %v, expected %v", test.tol, test.a, test.b, got, test.equal)
+		}
+		if got := test.tol.equal(test.b, test.a); got != test.equal {
+			t.Errorf("%+v.equal(%v, %v) = %v, expected %v", test.tol, test.b, test.a, got, test.equal)
+		}
+	}
+}
+
+func TestToleranceDiff(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		tol       Tolerance
+		want, got []float64
+		diff      string
+	}{
+		{name: "match", tol: Tolerance{Abs: 0.5}, want: []float64{1, 2}, got: []float64{1.25, 2}, diff: ""},
+		{name: "length", want: []float64{1, 2}, got: []float64{1}, diff: "length 1, expected 2"},
+		{
+			name: "worst first",
+			want: []float64{1, 2, 3},
+			got:  []float64{1.5, 2, 1},
+			diff: "2 of 3 elements differ; [2] expected 3, got 1 (abs err 2, rel err 0.667); [0] expected 1, got 1.5 (abs err 0.5, rel err 0.333)",
+		},
+		{
+			name: "excess over tolerance first",
+			tol:  Tolerance{Rel: 0.1},
+			want: []float64{100, 1},
+			got:  []float64{120, 1.5},
+			diff: "2 of 2 elements differ; [1] expected 1, got 1.5 (abs err 0.5, rel err 0.333); [0] expected 100, got 120 (abs err 20, rel err 0.167)",
+		},
+		{
+			name: "NaN",
+			want: []float64{1},
+			got:  []float64{math.NaN()},
+			diff: "1 of 1 elements differ; [0] expected 1, got NaN (abs err +Inf, rel err NaN)",
+		},
+	} {
+		if d := test.tol.diff(test.want, test.got); d != test.diff {
+			t.Errorf("%v: diff is %q, expected %q", test.name, d, test.diff)
+		}
+	}
+}
+
+func TestToleranceDiffTruncates(t *testing.T) {
+	want := make([]float64, 2*maxDiffs)
+	got := make([]float64, len(want))
+	for i := range got {
+		got[i] = float64(i + 1)
+	}
+	d := Tolerance{}.diff(want, got)
+	if !strings.HasPrefix(d, "10 of 10 elements differ; [9] expected 0, got 10") {
+		t.Errorf("diff doesn't start with the worst element: %q", d)
+	}
+	if n := strings.Count(d, "expected"); n != maxDiffs {
+		t.Errorf("diff lists %v elements, expected %v", n, maxDiffs)
+	}
+	if !strings.HasSuffix(d, "; ...") {
+		t.Errorf("truncated diff doesn't end with an ellipsis: %q", d)
+	}
+}
+
+func TestToleranceDiffRows(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		want, got [][]float64
+		diff      string
+	}{
+		{name: "match", want: [][]float64{{1}, {2, 3}}, got: [][]float64{{1}, {2, 3}}, diff: ""},
+		{name: "rows", want: [][]float64{{1}, {2}}, got: [][]float64{{1}}, diff: "1 rows, expected 2"},
+		{name: "row length", want: [][]float64{{1}, {2}}, got: [][]float64{{1}, {2, 3}}, diff: "row 1 has length 2, expected 1"},
+		{
+			name: "element",
+			want: [][]float64{{1, 2}, {3, 4}},
+			got:  [][]float64{{1, 2}, {3, 5}},
+			diff: "1 of 4 elements differ; [1][1] expected 4, got 5 (abs err 1, rel err 0.2)",
+		},
+	} {
+		if d := (Tolerance{}).diffRows(test.want, test.got); d != test.diff {
+			t.Errorf("%v: diffRows is %q, expected %q", test.name, d, test.diff)
+		}
+	}
+}
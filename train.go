@@ -0,0 +1,353 @@
+package regtest
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+type Trainer interface {
+	Train(inputs, outputs [][]float64, weights []float64) error
+	ParameterGetterSetter
+	InputOutputer
+}
+
+// StructureFunc returns an error if the dimensions of a model violate a structural
+// relationship, for example the number of parameters of a linear model.
+type StructureFunc func(numParameters, inputDim, outputDim int) error
+
+// LinearStructure returns a StructureFunc for a linear model with a weight for each
+// input/output pair and, if intercept is true, an intercept for each output.
+func LinearStructure(intercept bool) StructureFunc {
+	return func(numParameters, inputDim, outputDim int) error {
+		want := inputDim * outputDim
+		if intercept {
+			want += outputDim
+		}
+		if numParameters != want {
+			return fmt.Errorf("linear model with %v inputs and %v outputs has %v parameters, expected %v", inputDim, outputDim, numParameters, want)
+		}
+		return nil
+	}
+}
+
+// checkStructure reports an error if the Structure option is set and tr violates it
+func checkStructure(t *testing.T, tr Trainer, s *settings, when, name string) {
+	if s.structure == nil {
+		return
+	}
+	if err := s.structure(tr.NumParameters(), tr.InputDim(), tr.OutputDim()); err != nil {
+		t.Errorf("%v: %v: %v", name, when, err)
+	}
+}
+
+// TestTrain tests the Train contract. It checks that mismatched numbers of inputs,
+// outputs and weights are rejected (by panic or error), that nil weights are accepted,
+// that training on the valid data succeeds, and that NumParameters, InputDim and
+// OutputDim are unchanged by training. With the Structure option, the structure is
+// checked before and after training.
+func TestTrain(t *testing.T, tr Trainer, inputs, outputs [][]float64, name string, opts ...Option) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	if len(inputs) < 2 {
+		panic("at least two samples needed")
+	}
+	s := newSettings(opts)
+	checkStructure(t, tr, s, "after construction", name)
+	nSamples := len(inputs)
+	numParameters := tr.NumParameters()
+	inputDim := tr.InputDim()
+	outputDim := tr.OutputDim()
+
+	f := func(inputs, outputs [][]float64, weights []float64) func() error {
+		return func() error {
+			return tr.Train(inputs, outputs, weights)
+		}
+	}
+	if !rejects(f(inputs[:nSamples-1], outputs, nil)) {
+		t.Errorf("%v: Train did not reject fewer inputs than outputs", name)
+	}
+	if !rejects(f(inputs, outputs[:nSamples-1], nil)) {
+		t.Errorf("%v: Train did not reject fewer outputs than inputs", name)
+	}
+	if !rejects(f(inputs, outputs, make([]float64, nSamples-1))) {
+		t.Errorf("%v: Train did not reject weights of the wrong length", name)
+	}
+
+	var err error
+	if maybe(func() { err = tr.Train(inputs, outputs, nil) }) {
+		t.Errorf("%v: Train panicked with nil weights", name)
+		return
+	}
+	if err != nil {
+		t.Errorf("%v: Error training with nil weights: %v", name, err)
+		return
+	}
+
+	weights := make([]float64, nSamples)
+	for i := range weights {
+		weights[i] = 1
+	}
+	err = tr.Train(inputs, outputs, weights)
+	if err != nil {
+		t.Errorf("%v: Error training with weights: %v", name, err)
+		return
+	}
+
+	if tr.NumParameters() != numParameters {
+		t.Errorf("%v: NumParameters changed during training. Before %v, after %v", name, numParameters, tr.NumParameters())
+	}
+	if len(tr.Parameters(nil)) != tr.NumParameters() {
+		t.Errorf("%v: After training, length of Parameters() doesn't match NumParameters()", name)
+	}
+	TestInputOutputDim(t, tr, inputDim, outputDim, name+" after training")
+	checkStructure(t, tr, s, "after training", name)
+}
+
+// OnlineTrainer is a model that can be trained incrementally, one sample at a time
+type OnlineTrainer interface {
+	TrainOne(input, output []float64, weight float64) error
+	Predictor
+	ParameterGetterSetter
+}
+
+// TestOnlineTrainer feeds inputs and outputs to the model one sample at a time. After
+// each sample it checks that the model can still predict, that the prediction is
+// finite, and that NumParameters, InputDim and OutputDim are unchanged. With the
+// MatchBatch option, it also checks that full-batch training on the same data gives
+// the same parameters to within tolerance.
+func TestOnlineTrainer(t *testing.T, tr OnlineTrainer, inputs, outputs [][]float64, name string, opts ...Option) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	s := newSettings(opts)
+	numParameters := tr.NumParameters()
+	inputDim := tr.InputDim()
+	outputDim := tr.OutputDim()
+
+	for i := range inputs {
+		err := tr.TrainOne(inputs[i], outputs[i], 1)
+		if err != nil {
+			t.Errorf("%v: Error training on sample %v: %v", name, i, err)
+			return
+		}
+		pred, err := tr.Predict(inputs[i], nil)
+		if err != nil {
+			t.Errorf("%v: Error predicting after training on sample %v: %v", name, i, err)
+			return
+		}
+		for _, v := range pred {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("%v: Non-finite prediction after training on sample %v: %v", name, i, pred)
+				return
+			}
+		}
+		if tr.NumParameters() != numParameters || tr.InputDim() != inputDim || tr.OutputDim() != outputDim {
+			t.Errorf("%v: NumParameters, InputDim or OutputDim changed after training on sample %v", name, i)
+			return
+		}
+	}
+
+	if s.batch == nil {
+		return
+	}
+	err := s.batch.Train(inputs, outputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error training batch model: %v", name, err)
+		return
+	}
+	online := tr.Parameters(nil)
+	batch := s.batch.Parameters(nil)
+	if d := s.stochasticTol(s.batchTol).diff(batch, online); d != "" {
+		t.Errorf("%v: incremental and batch training disagree: %v", name, d)
+	}
+}
+
+// Resetter is a trainable model that can be restored to its untrained state
+type Resetter interface {
+	Trainer
+	Predictor
+	Reset()
+}
+
+// TestReset tests that Reset restores a trained model to its untrained state.
+// fresh must be a newly constructed, never trained model configured identically to r.
+// After training r and calling Reset, r must have the same parameters as fresh, and
+// training both on the data must give the same parameters and predictions. Training
+// is assumed to be deterministic; the comparisons are exact unless the Tol option is
+// given.
+func TestReset(t *testing.T, r, fresh Resetter, inputs, outputs [][]float64, name string, opts ...Option) {
+	s := newSettings(opts)
+	err := r.Train(inputs, outputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error training before reset: %v", name, err)
+		return
+	}
+	r.Reset()
+	if r.NumParameters() != fresh.NumParameters() {
+		t.Errorf("%v: NumParameters after reset doesn't match a fresh model. Expected %v, found %v", name, fresh.NumParameters(), r.NumParameters())
+		return
+	}
+	if d := s.tol.diff(fresh.Parameters(nil), r.Parameters(nil)); d != "" {
+		t.Errorf("%v: Parameters after reset don't match a fresh model: %v", name, d)
+	}
+
+	err = r.Train(inputs, outputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error training after reset: %v", name, err)
+		return
+	}
+	err = fresh.Train(inputs, outputs, nil)
+	if err != nil {
+		t.Errorf("%v: Error training fresh model: %v", name, err)
+		return
+	}
+	if d := s.tol.diff(fresh.Parameters(nil), r.Parameters(nil)); d != "" {
+		t.Errorf("%v: Parameters after reset and training don't match a freshly trained model: %v", name, d)
+	}
+	pred, err := predictAll(r, inputs)
+	if err != nil {
+		t.Errorf("%v: Error predicting after reset: %v", name, err)
+		return
+	}
+	freshPred, err := predictAll(fresh, inputs)
+	if err != nil {
+		t.Errorf("%v: Error predicting with fresh model: %v", name, err)
+		return
+	}
+	if d := s.tol.diffRows(freshPred, pred); d != "" {
+		t.Errorf("%v: Predictions after reset and training don't match a freshly trained model: %v", name, d)
+	}
+}
+
+// PredictTrainer is a Trainer that can predict
+type PredictTrainer interface {
+	Trainer
+	Predictor
+}
+
+// TestWeightedTrain tests the semantics of sample weights. newModel must return a
+// fresh, identically configured model each time it is called. It checks, by comparing
+// predictions on the inputs to within tol, that giving samples zero weight is the same
+// as removing them, that all-one weights are the same as nil weights, and that doubling
+// the weight of a sample is the same as duplicating it. A tolerance set for the
+// Stochastic profile overrides tol.
+func TestWeightedTrain(t *testing.T, newModel func() PredictTrainer, inputs, outputs [][]float64, tol float64, name string, opts ...Option) {
+	cmp := newSettings(opts).stochasticTol(tol)
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	if len(inputs) < 3 {
+		panic("at least three samples needed")
+	}
+	nSamples := len(inputs)
+
+	// fitOn trains a fresh model and returns its predictions on the original inputs
+	fitOn := func(trainIn, trainOut [][]float64, weights []float64) [][]float64 {
+		m := newModel()
+		err := m.Train(trainIn, trainOut, weights)
+		if err != nil {
+			t.Errorf("%v: Error training: %v", name, err)
+			return nil
+		}
+		pred, err := predictAll(m, inputs)
+		if err != nil {
+			t.Errorf("%v: Error predicting: %v", name, err)
+			return nil
+		}
+		return pred
+	}
+
+	unweighted := fitOn(inputs, outputs, nil)
+	if unweighted == nil {
+		return
+	}
+
+	ones := make([]float64, nSamples)
+	for i := range ones {
+		ones[i] = 1
+	}
+	if pred := fitOn(inputs, outputs, ones); pred == nil {
+		return
+	} else if d := cmp.diffRows(unweighted, pred); d != "" {
+		t.Errorf("%v: Training with all-one weights differs from training with nil weights: %v", name, d)
+	}
+
+	// Give every third sample zero weight
+	zeroed := make([]float64, nSamples)
+	var keptIn, keptOut [][]float64
+	for i := range zeroed {
+		if i%3 == 0 {
+			continue
+		}
+		zeroed[i] = 1
+		keptIn = append(keptIn, inputs[i])
+		keptOut = append(keptOut, outputs[i])
+	}
+	zeroPred := fitOn(inputs, outputs, zeroed)
+	removedPred := fitOn(keptIn, keptOut, nil)
+	if zeroPred == nil || removedPred == nil {
+		return
+	}
+	if d := cmp.diffRows(removedPred, zeroPred); d != "" {
+		t.Errorf("%v: Training with zero-weighted samples differs from training with them removed: %v", name, d)
+	}
+
+	// Double the weight of the first sample
+	doubled := make([]float64, nSamples)
+	copy(doubled, ones)
+	doubled[0] = 2
+	dupIn := append([][]float64{inputs[0]}, inputs...)
+	dupOut := append([][]float64{outputs[0]}, outputs...)
+	doubledPred := fitOn(inputs, outputs, doubled)
+	dupPred := fitOn(dupIn, dupOut, nil)
+	if doubledPred == nil || dupPred == nil {
+		return
+	}
+	if d := cmp.diffRows(dupPred, doubledPred); d != "" {
+		t.Errorf("%v: Doubling the weight of a sample differs from duplicating it: %v", name, d)
+	}
+}
+
+// trainingFractions are the fractions of the data set used by TestDimsStable
+var trainingFractions = []float64{0.25, 0.5, 1}
+
+// TestDimsStable trains tr on increasingly large prefixes of the data set and checks
+// that NumParameters, InputDim and OutputDim are the same as before training, or, with
+// the ExpectedDims option, that they are as declared for each training set size. With
+// the Structure option, the structure is checked before and after each training.
+func TestDimsStable(t *testing.T, tr Trainer, inputs, outputs [][]float64, name string, opts ...Option) {
+	if len(inputs) != len(outputs) {
+		panic("inputs and outputs have different number of rows")
+	}
+	s := newSettings(opts)
+	checkStructure(t, tr, s, "after construction", name)
+	numParameters := tr.NumParameters()
+	inputDim := tr.InputDim()
+	outputDim := tr.OutputDim()
+	for _, frac := range trainingFractions {
+		n := int(frac * float64(len(inputs)))
+		if n == 0 {
+			continue
+		}
+		err := tr.Train(inputs[:n], outputs[:n], nil)
+		if err != nil {
+			t.Errorf("%v: Error training on %v samples: %v", name, n, err)
+			return
+		}
+		if s.expectedDims != nil {
+			numParameters, inputDim, outputDim = s.expectedDims(n)
+		}
+		if tr.NumParameters() != numParameters {
+			t.Errorf("%v: After training on %v samples, NumParameters is %v, expected %v", name, n, tr.NumParameters(), numParameters)
+		}
+		if len(tr.Parameters(nil)) != tr.NumParameters() {
+			t.Errorf("%v: After training on %v samples, length of Parameters() doesn't match NumParameters()", name, n)
+		}
+		if tr.InputDim() != inputDim || tr.OutputDim() != outputDim {
+			t.Errorf("%v: After training on %v samples, dimensions are %v×%v, expected %v×%v", name, n, tr.InputDim(), tr.OutputDim(), inputDim, outputDim)
+		}
+		checkStructure(t, tr, s, fmt.Sprintf("after training on %v samples", n), name)
+	}
+}
@@ -0,0 +1,54 @@
+package regtest
+
+import (
+	"math/rand"
+
+	"github.com/gonum/matrix/mat64"
+)
+
+// randomProbes returns n random inputs of length dim drawn from rnd
+func randomProbes(n, dim int, rnd *rand.Rand) [][]float64 {
+	probes := make([][]float64, n)
+	for i := range probes {
+		probes[i] = make([]float64, dim)
+		for j := range probes[i] {
+			probes[i][j] = rnd.NormFloat64()
+		}
+	}
+	return probes
+}
+
+// predictAll returns the predictions of p on each of the inputs
+func predictAll(p Predictor, inputs [][]float64) ([][]float64, error) {
+	outputs := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		var err error
+		outputs[i], err = p.Predict(input, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// randomParameters returns a random parameter vector for p drawn from rnd
+func randomParameters(p ParameterGetterSetter, rnd *rand.Rand) []float64 {
+	params := make([]float64, p.NumParameters())
+	for i := range params {
+		params[i] = rnd.NormFloat64()
+	}
+	return params
+}
+
+// rows returns the elements of m as a slice of rows, for printing
+func rows(m mat64.Matrix) [][]float64 {
+	r, c := m.Dims()
+	s := make([][]float64, r)
+	for i := range s {
+		s[i] = make([]float64, c)
+		for j := range s[i] {
+			s[i][j] = m.At(i, j)
+		}
+	}
+	return s
+}